@@ -0,0 +1,53 @@
+package main
+
+import "fmt"
+
+// checkCompat records, before opcode runs, a suggestion when its result
+// depends on a quirk and the two settings would give different results here.
+// Each suggestion is recorded once, at the first address it applies to.
+func (c *Chip8) checkCompat(opcode uint16) {
+	x := (opcode & 0x0F00) >> 8
+	y := (opcode & 0x00F0) >> 4
+	m := mnemonic(opcode)
+	var quirk string
+	on := false
+	switch m {
+	case "8XY6", "8XYE":
+		if x != y && c.V[x] != c.V[y] {
+			quirk, on = "ShiftUsesVY", c.Quirks.ShiftUsesVY
+		}
+	case "8XY1", "8XY2", "8XY3":
+		if x != 0xF && c.V[0xF] != 0 {
+			quirk, on = "LogicResetsVF", c.Quirks.LogicResetsVF
+		}
+	case "FX55", "FX65":
+		quirk, on = "IncrementI", c.Quirks.IncrementI
+	case "BNNN":
+		if x != 0 && c.V[x] != c.V[0] {
+			quirk, on = "JumpUsesVX", c.Quirks.JumpUsesVX
+		}
+	}
+	if quirk == "" {
+		return
+	}
+	action := "enabling"
+	if on {
+		action = "disabling"
+	}
+	key := m + " " + quirk
+	if c.warned[key] {
+		return
+	}
+	if c.warned == nil {
+		c.warned = make(map[string]bool)
+	}
+	c.warned[key] = true
+	c.warnings = append(c.warnings, fmt.Sprintf("%03X: %s depends on %s; if the ROM misbehaves try %s it",
+		c.opPC, m, quirk, action))
+}
+
+// Warnings returns the quirk suggestions collected while CompatWarnings was
+// set, oldest first.
+func (c *Chip8) Warnings() []string {
+	return append([]string(nil), c.warnings...)
+}
@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestCompatWarningForShift(t *testing.T) {
+	c := newTestChip(t, 0x6003, 0x6105, 0x8016, 0x8016)
+	c.Quirks.ShiftUsesVY = false
+	c.CompatWarnings = true
+	step(t, c, 4)
+	want := "204: 8XY6 depends on ShiftUsesVY; if the ROM misbehaves try enabling it"
+	if w := c.Warnings(); len(w) != 1 || w[0] != want {
+		t.Errorf("Warnings = %q, want [%q]", w, want)
+	}
+}
+
+func TestCompatWarningsOff(t *testing.T) {
+	c := newTestChip(t, 0x6003, 0x6105, 0x8016)
+	step(t, c, 3)
+	if w := c.Warnings(); len(w) != 0 {
+		t.Errorf("Warnings = %q with CompatWarnings off", w)
+	}
+}
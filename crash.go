@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+type crashFrame struct {
+	display [128][64]byte
+	hires   bool
+}
+
+// captureFrame records the display at the start of each RunFrame, keeping the
+// last CrashFrames frames.
+func (c *Chip8) captureFrame() {
+	if c.CrashFrames <= 0 {
+		c.crashRing = nil
+		return
+	}
+	c.crashRing = append(c.crashRing, crashFrame{c.display, c.hires})
+	if n := len(c.crashRing) - c.CrashFrames; n > 0 {
+		c.crashRing = append(c.crashRing[:0], c.crashRing[n:]...)
+	}
+}
+
+// CrashDump writes the recorded frames, oldest first, followed by the
+// register state, for attaching to a bug report after RunFrame fails.
+func (c *Chip8) CrashDump(w io.Writer) error {
+	for i, f := range c.crashRing {
+		width, height := 64, 32
+		if f.hires {
+			width, height = 128, 64
+		}
+		if _, err := fmt.Fprintf(w, "frame -%d\n", len(c.crashRing)-i); err != nil {
+			return err
+		}
+		row := make([]byte, width+1)
+		row[width] = '\n'
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				row[x] = '.'
+				if f.display[x][y] != 0 {
+					row[x] = '#'
+				}
+			}
+			if _, err := w.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	s := c.State()
+	fmt.Fprintf(w, "PC=%03X I=%03X SP=%X DT=%02X ST=%02X", s.PC, s.I, s.SP, s.DT, s.ST)
+	if c.Faulted {
+		fmt.Fprintf(w, " fault=%03X", c.faultPC)
+	}
+	fmt.Fprintln(w)
+	for i, v := range s.V {
+		fmt.Fprintf(w, "V%X=%02X", i, v)
+		if i%8 == 7 {
+			fmt.Fprintln(w)
+		} else {
+			fmt.Fprint(w, " ")
+		}
+	}
+	_, err := fmt.Fprintf(w, "stack=%03X\n", s.Stack)
+	return err
+}
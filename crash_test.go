@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCrashDumpFrames(t *testing.T) {
+	c := newTestChip(t, 0x7001, 0x7001, 0x7001, 0x7001, 0x7001, 0x00EE)
+	c.InstructionsPerFrame = 1
+	c.CrashFrames = 3
+	var err error
+	for i := 0; i < 10 && err == nil; i++ {
+		err = c.RunFrame()
+	}
+	if err == nil {
+		t.Fatal("program did not fault")
+	}
+	var b strings.Builder
+	if err := c.CrashDump(&b); err != nil {
+		t.Fatal(err)
+	}
+	dump := b.String()
+	if n := strings.Count(dump, "frame -"); n != 3 {
+		t.Errorf("dump has %d frames, want 3", n)
+	}
+	if strings.Count(dump, "\n") != 3*33+4 {
+		t.Errorf("dump has %d lines, want %d", strings.Count(dump, "\n"), 3*33+4)
+	}
+	if !strings.Contains(dump, "fault=20A") || !strings.Contains(dump, "V0=05") {
+		t.Errorf("register state missing from dump:\n%s", dump[strings.LastIndex(dump, "frame -1"):])
+	}
+}
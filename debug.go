@@ -0,0 +1,300 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type State struct {
+	PC    uint16
+	I     uint16
+	SP    byte
+	V     [16]byte
+	DT    byte
+	ST    byte
+	Stack []uint16
+
+	Memory map[uint16]byte // only used by SetState, bytes to write
+}
+
+// State returns a copy of the registers, timers and stack. Like SetState,
+// GetRegister, SetRegister, ReadRange, WriteMem and Undo it is serialized
+// with Step and RunFrame, and so with RunContext, so a debugger goroutine can
+// call it while another goroutine runs the machine. Callbacks such as
+// OnDebugTick run inside Step and RunFrame and must not call these.
+func (c *Chip8) State() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state()
+}
+
+func (c *Chip8) state() State {
+	return State{
+		PC:    c.PC,
+		I:     c.I,
+		SP:    c.SP,
+		V:     c.V,
+		DT:    c.DelayTimer(),
+		ST:    c.SoundTimer(),
+		Stack: append([]uint16(nil), c.stack[:c.SP]...),
+	}
+}
+
+// SetState is the inverse of State: it loads the registers, stack, timers
+// and any Memory bytes into the machine. Stack entries beyond SP are kept as
+// given. Nothing is changed if a value is out of range.
+func (c *Chip8) SetState(s State) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.setState(s)
+}
+
+func (c *Chip8) setState(s State) error {
+	if int(s.PC)+1 >= len(c.memory) {
+		return fmt.Errorf("PC out of range: %04X", s.PC)
+	}
+	if len(s.Stack) > len(c.stack) || int(s.SP) > len(c.stack) {
+		return fmt.Errorf("stack of %d with SP %d exceeds %d levels", len(s.Stack), s.SP, len(c.stack))
+	}
+	for addr := range s.Memory {
+		if int(addr) >= len(c.memory) {
+			return fmt.Errorf("memory address out of range: %04X", addr)
+		}
+	}
+	c.PC = s.PC
+	c.I = s.I
+	c.SP = s.SP
+	c.V = s.V
+	c.SetDelayTimer(s.DT)
+	c.SetSoundTimer(s.ST)
+	for i := range c.stack {
+		c.stack[i] = 0
+	}
+	copy(c.stack, s.Stack)
+	for addr, v := range s.Memory {
+		c.memory[addr] = v
+	}
+	return nil
+}
+
+func (c *Chip8) ReadRange(addr uint16, n int) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n < 0 || int(addr)+n > len(c.memory) {
+		return nil, fmt.Errorf("read out of bounds: %04X+%d", addr, n)
+	}
+	return append([]byte(nil), c.memory[addr:int(addr)+n]...), nil
+}
+
+// WriteMem copies data into memory at addr, such as a patched instruction
+// while paused. Nothing is cached from memory, so the next Step runs the new
+// bytes. Nothing is written if data would run past the end of memory.
+func (c *Chip8) WriteMem(addr uint16, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if int(addr)+len(data) > len(c.memory) {
+		return fmt.Errorf("write out of bounds: %04X+%d", addr, len(data))
+	}
+	copy(c.memory[addr:], data)
+	return nil
+}
+
+// Step executes a single instruction. With UndoDepth set it first records
+// what the instruction may change, for Undo.
+func (c *Chip8) Step() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.UndoDepth > 0 {
+		c.pushUndo()
+	}
+	return c.Cycle()
+}
+
+func (c *Chip8) SetBreakpoint(addr uint16) {
+	if c.breakpoints == nil {
+		c.breakpoints = make(map[uint16]bool)
+	}
+	c.breakpoints[addr] = true
+}
+
+func (c *Chip8) ClearBreakpoint(addr uint16) {
+	delete(c.breakpoints, addr)
+}
+
+// RunResult summarizes a Run, RunUntil or RunToHalt call.
+type RunResult struct {
+	Instructions int           // executed by this run
+	Cycles       uint64        // total since Reset, as in Chip8.Cycles
+	Halted       bool          // RunToHalt: stopped on a jump to itself, or SuspectedIdle with DetectIdle
+	Breakpoint   bool          // stopped before an instruction at a breakpoint
+	Reached      bool          // RunUntil: stopped with PC at the address asked for
+	Err          error         // the instruction error that ended the run, if any
+	PC           uint16        // where execution stopped
+	Elapsed      time.Duration // wall time taken
+}
+
+// Run executes up to maxCycles instructions, stopping before any instruction
+// at a breakpoint, or after an instruction fails.
+func (c *Chip8) Run(maxCycles int) RunResult {
+	start := c.now()
+	var r RunResult
+	for r.Instructions < maxCycles {
+		if r.Instructions > 0 && c.breakpoints[c.PC] {
+			break
+		}
+		r.Instructions++
+		if r.Err = c.Step(); r.Err != nil {
+			break
+		}
+	}
+	r.Breakpoint = r.Err == nil && c.breakpoints[c.PC]
+	return c.finishRun(r, start)
+}
+
+// RunUntil executes until PC equals addr, a breakpoint is hit or maxCycles
+// instructions have run. PC is checked before each instruction executes;
+// Reached reports whether addr was reached.
+func (c *Chip8) RunUntil(addr uint16, maxCycles int) RunResult {
+	start := c.now()
+	var r RunResult
+	for r.Instructions < maxCycles && c.PC != addr {
+		if r.Instructions > 0 && c.breakpoints[c.PC] {
+			r.Breakpoint = true
+			break
+		}
+		r.Instructions++
+		if r.Err = c.Step(); r.Err != nil {
+			break
+		}
+	}
+	r.Reached = r.Err == nil && c.PC == addr
+	return c.finishRun(r, start)
+}
+
+// RunToHalt executes up to maxCycles instructions like Run, also stopping
+// when the program halts, the usual end of a test ROM.
+func (c *Chip8) RunToHalt(maxCycles int) RunResult {
+	start := c.now()
+	var r RunResult
+	for r.Instructions < maxCycles {
+		if r.Instructions > 0 && c.breakpoints[c.PC] {
+			r.Breakpoint = true
+			break
+		}
+		r.Instructions++
+		if r.Err = c.Step(); r.Err != nil {
+			break
+		}
+		if c.halted() || (c.DetectIdle && c.SuspectedIdle()) {
+			r.Halted = true
+			break
+		}
+	}
+	return c.finishRun(r, start)
+}
+
+func (c *Chip8) finishRun(r RunResult, start time.Time) RunResult {
+	r.Cycles = c.Cycles
+	r.PC = c.PC
+	r.Elapsed = c.now().Sub(start)
+	return r
+}
+
+// halted reports whether the last instruction was a 1NNN jump to itself.
+func (c *Chip8) halted() bool {
+	if c.PC != c.opPC || int(c.PC)+1 >= len(c.memory) {
+		return false
+	}
+	return uint16(c.memory[c.PC])<<8|uint16(c.memory[c.PC+1]) == 0x1000|c.PC
+}
+
+// GetRegister reads a register by name: V0..VF, I, PC, SP, DT or ST.
+func (c *Chip8) GetRegister(name string) (uint16, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch name = strings.ToUpper(name); name {
+	case "I":
+		return c.I, nil
+	case "PC":
+		return c.PC, nil
+	case "SP":
+		return uint16(c.SP), nil
+	case "DT":
+		return uint16(c.DelayTimer()), nil
+	case "ST":
+		return uint16(c.SoundTimer()), nil
+	}
+	if r, ok := vRegister(name); ok {
+		return uint16(c.V[r]), nil
+	}
+	return 0, fmt.Errorf("unknown register: %s", name)
+}
+
+// SetRegister writes a register by name, rejecting values that do not fit.
+// The change is seen by the next Step.
+func (c *Chip8) SetRegister(name string, val uint16) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	name = strings.ToUpper(name)
+	limit := uint16(0xFF)
+	switch name {
+	case "I":
+		limit = 0xFFFF
+	case "PC":
+		limit = uint16(len(c.memory) - 2)
+	case "SP":
+		limit = uint16(len(c.stack))
+	case "DT", "ST":
+	default:
+		if _, ok := vRegister(name); !ok {
+			return fmt.Errorf("unknown register: %s", name)
+		}
+	}
+	if val > limit {
+		return fmt.Errorf("value %X out of range for %s", val, name)
+	}
+	switch name {
+	case "I":
+		c.I = val
+	case "PC":
+		c.PC = val
+	case "SP":
+		c.SP = byte(val)
+	case "DT":
+		c.SetDelayTimer(byte(val))
+	case "ST":
+		c.SetSoundTimer(byte(val))
+	default:
+		r, _ := vRegister(name)
+		c.V[r] = byte(val)
+	}
+	return nil
+}
+
+func vRegister(name string) (int, bool) {
+	if len(name) != 2 || name[0] != 'V' {
+		return 0, false
+	}
+	r, ok := hexKey(name[1])
+	return int(r), ok
+}
+
+// Preview returns the next instruction to execute without changing any state.
+func (c *Chip8) Preview() (pc uint16, opcode uint16, mnemonic string) {
+	opcode = c.Peek()
+	return c.PC, opcode, disassemble(opcode)
+}
+
+// ExecuteAll runs opcodes directly against the current state without
+// fetching them from memory, so PC only changes when an opcode itself moves
+// it (jumps, calls, skips). It stops at the first error.
+func (c *Chip8) ExecuteAll(opcodes ...uint16) error {
+	for _, opcode := range opcodes {
+		c.opPC = c.PC
+		if err := c.Execute(opcode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRunToHalt(t *testing.T) {
+	c := newTestChip(t, 0x6108, 0x6201, 0x1204)
+	r := c.RunToHalt(100)
+	if !r.Halted || r.Err != nil || r.Instructions != 3 || r.Cycles != 3 || r.PC != 0x204 {
+		t.Errorf("RunToHalt = %+v", r)
+	}
+}
+
+func TestRunToHaltError(t *testing.T) {
+	c := newTestChip(t, 0x6108, 0x00EE) // return with an empty stack
+	r := c.RunToHalt(100)
+	if r.Err == nil || r.Halted || r.Instructions != 2 {
+		t.Errorf("RunToHalt = %+v, want an error after 2 instructions", r)
+	}
+}
+
+func TestRunStopsAtBreakpoint(t *testing.T) {
+	c := newTestChip(t, 0x6001, 0x6002, 0x6003, 0x1206)
+	c.SetBreakpoint(0x204)
+	r := c.Run(100)
+	if !r.Breakpoint || r.PC != 0x204 || r.Instructions != 2 || c.V[0] != 2 {
+		t.Errorf("Run = %+v, V0=%d", r, c.V[0])
+	}
+}
+
+func TestRunUntil(t *testing.T) {
+	c := newTestChip(t, 0x6001, 0x6002, 0x6003, 0x1206)
+	r := c.RunUntil(0x206, 100)
+	if !r.Reached || r.Instructions != 3 || c.V[0] != 3 {
+		t.Errorf("RunUntil = %+v", r)
+	}
+	r = c.RunUntil(0x200, 10)
+	if r.Reached || r.Instructions != 10 {
+		t.Errorf("RunUntil unreachable = %+v", r)
+	}
+}
+
+func TestRunUntilLoopAddress(t *testing.T) {
+	// V0 counts to 5 in a loop at 0x202, then falls through to 0x208.
+	c := newTestChip(t, 0x6000, 0x7001, 0x3005, 0x1202, 0x1208)
+	r := c.RunUntil(0x208, 100)
+	if !r.Reached || c.PC != 0x208 || c.V[0] != 5 {
+		t.Errorf("RunUntil = %+v, PC=%03X V0=%d", r, c.PC, c.V[0])
+	}
+}
+
+func TestRunUntilStopsAtEarlierBreakpoint(t *testing.T) {
+	c := newTestChip(t, 0x6001, 0x6002, 0x6003, 0x1206)
+	c.SetBreakpoint(0x202)
+	r := c.RunUntil(0x206, 100)
+	if r.Reached || !r.Breakpoint || c.PC != 0x202 {
+		t.Errorf("RunUntil = %+v, want a stop at the breakpoint 202", r)
+	}
+}
+
+func TestSetStateTransition(t *testing.T) {
+	c := newTestChip(t)
+	s := State{
+		PC:     0x300,
+		I:      0x400,
+		SP:     1,
+		DT:     10,
+		Stack:  []uint16{0x250},
+		Memory: map[uint16]byte{0x300: 0xF2, 0x301: 0x33}, // FX33 BCD of V2
+	}
+	s.V[2] = 157
+	if err := c.SetState(s); err != nil {
+		t.Fatal(err)
+	}
+	step(t, c, 1)
+	got := c.State()
+	if got.PC != 0x302 || got.I != 0x400 || got.SP != 1 || got.DT != 10 || got.Stack[0] != 0x250 || got.V[2] != 157 {
+		t.Errorf("State after FX33 = %+v", got)
+	}
+	if bcd, _ := c.ReadRange(0x400, 3); string(bcd) != "\x01\x05\x07" {
+		t.Errorf("BCD at I = % X, want 01 05 07", bcd)
+	}
+}
+
+func TestSetStateValidates(t *testing.T) {
+	c := newTestChip(t)
+	for _, s := range []State{
+		{PC: 0xFFF},
+		{PC: 0x200, SP: 17},
+		{PC: 0x200, Stack: make([]uint16, 17)},
+		{PC: 0x200, Memory: map[uint16]byte{0x1000: 1}},
+	} {
+		if err := c.SetState(s); err == nil {
+			t.Errorf("SetState(%+v) succeeded", s)
+		}
+	}
+}
+
+func TestResetZeroesCounters(t *testing.T) {
+	c := newTestChip(t, 0x6001, 0x1202)
+	step(t, c, 2)
+	c.Reset()
+	if c.Cycles != 0 || len(c.OpcodeCounts()) != 0 {
+		t.Errorf("after Reset Cycles=%d counts=%v", c.Cycles, c.OpcodeCounts())
+	}
+	if r := c.RunToHalt(10); r.Cycles != 2 {
+		t.Errorf("Cycles after Reset and a run = %d, want 2", r.Cycles)
+	}
+}
+
+// TestConcurrentStepAndState hammers Step from one goroutine while another
+// reads through the locked debugger API; run it with -race.
+// TestConcurrentStepAndState reads and writes registers from this goroutine
+// while each of the ways to run the machine drives it from another; run it
+// with -race.
+func TestConcurrentStepAndState(t *testing.T) {
+	loop := func(run func(c *Chip8) error) func(context.Context, *Chip8) error {
+		return func(ctx context.Context, c *Chip8) error {
+			for ctx.Err() == nil {
+				if err := run(c); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	for _, tc := range []struct {
+		name string
+		run  func(context.Context, *Chip8) error
+	}{
+		{"Step", loop((*Chip8).Step)},
+		{"RunFrame", loop((*Chip8).RunFrame)},
+		{"RunContext", func(ctx context.Context, c *Chip8) error {
+			if err := c.RunContext(ctx); err != context.Canceled {
+				return err
+			}
+			return nil
+		}},
+	} {
+		c := newTestChip(t, 0x7001, 0xA300, 0xF055, 0x1200)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- tc.run(ctx, c) }()
+		for i := 0; i < 2000; i++ {
+			s := c.State()
+			if s.PC < 0x200 || s.PC > 0x206 {
+				t.Fatalf("%s: State PC = %03X", tc.name, s.PC)
+			}
+			if _, err := c.GetRegister("V0"); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.SetRegister("V1", uint16(i&0xFF)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.ReadRange(0x300, 1); err != nil {
+				t.Fatal(err)
+			}
+		}
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+	}
+}
+
+func TestRegisterByName(t *testing.T) {
+	c := newTestChip(t)
+	for _, tc := range []struct {
+		name string
+		val  uint16
+	}{{"v3", 0x42}, {"VF", 1}, {"I", 0xFFF}, {"pc", 0x300}, {"DT", 9}, {"ST", 0}} {
+		if err := c.SetRegister(tc.name, tc.val); err != nil {
+			t.Fatalf("SetRegister(%s): %v", tc.name, err)
+		}
+		got, err := c.GetRegister(tc.name)
+		if err != nil || got != tc.val {
+			t.Errorf("GetRegister(%s) = %X, %v, want %X", tc.name, got, err, tc.val)
+		}
+	}
+	if err := c.SetRegister("V0", 0x100); err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Errorf("SetRegister(V0, 100) = %v, want out of range", err)
+	}
+	if err := c.SetRegister("FOO", 0x100); err == nil || !strings.Contains(err.Error(), "unknown register") {
+		t.Errorf("SetRegister(FOO, 100) = %v, want unknown register", err)
+	}
+	if _, err := c.GetRegister("VG"); err == nil {
+		t.Error("GetRegister(VG) succeeded")
+	}
+}
+
+func TestPreviewMatchesStep(t *testing.T) {
+	c := newTestChip(t, 0x6005, 0x3005, 0x6001, 0x220A, 0x1208, 0x00EE)
+	var trace strings.Builder
+	c.WriteTrace(&trace)
+	for i := 0; i < 5; i++ {
+		before := mustSnapshot(t, c)
+		pc, opcode, mnemonic := c.Preview()
+		if after := mustSnapshot(t, c); !bytes.Equal(before, after) {
+			t.Fatal("Preview changed the machine state")
+		}
+		if mnemonic != disassemble(opcode) {
+			t.Errorf("Preview mnemonic %q for %04X", mnemonic, opcode)
+		}
+		trace.Reset()
+		step(t, c, 1)
+		if want := fmt.Sprintf("%d %03X %04X", i+1, pc, opcode); !strings.HasPrefix(trace.String(), want) {
+			t.Errorf("Preview said %03X %04X, Step ran %q", pc, opcode, trace.String())
+		}
+	}
+}
+
+func TestExecuteAll(t *testing.T) {
+	c := newTestChip(t)
+	if err := c.ExecuteAll(0x60F0, 0x6120, 0x8014); err != nil {
+		t.Fatal(err)
+	}
+	if c.V[0] != 0x10 || c.V[0xF] != 1 || c.PC != 0x200 {
+		t.Errorf("V0=%02X VF=%d PC=%03X, want 10, 1 and 200", c.V[0], c.V[0xF], c.PC)
+	}
+	if err := c.ExecuteAll(0x1300); err != nil || c.PC != 0x300 {
+		t.Errorf("ExecuteAll jump: err=%v PC=%03X", err, c.PC)
+	}
+}
+
+func TestDebugEveryCadence(t *testing.T) {
+	for _, every := range []int{0, 1, 3} {
+		c := newTestChip(t, 0x7001, 0x1200)
+		c.DebugEvery = every
+		var at []uint64
+		c.OnDebugTick = func(c *Chip8) { at = append(at, c.Cycles) }
+		step(t, c, 9)
+		var want []uint64
+		for n := 1; every > 0 && n <= 9; n++ {
+			if n%every == 0 {
+				want = append(want, uint64(n))
+			}
+		}
+		if fmt.Sprint(at) != fmt.Sprint(want) {
+			t.Errorf("DebugEvery=%d: ticks at cycles %v, want %v", every, at, want)
+		}
+	}
+}
+
+func TestWriteMemPatchesNextInstruction(t *testing.T) {
+	c := newTestChip(t, 0x6001, 0x6102, 0x1204)
+	step(t, c, 1)
+	if err := c.WriteMem(0x202, []byte{0x61, 0x99}); err != nil {
+		t.Fatal(err)
+	}
+	step(t, c, 1)
+	if c.V[1] != 0x99 {
+		t.Errorf("patched instruction not run: V1=%02X", c.V[1])
+	}
+	if err := c.WriteMem(0xFFF, []byte{1, 2}); err == nil || c.memory[0xFFF] != 0 {
+		t.Errorf("write past memory: err=%v memory[FFF]=%02X", err, c.memory[0xFFF])
+	}
+}
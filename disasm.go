@@ -0,0 +1,211 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// disassemble renders a single opcode as assembly text.
+func disassemble(opcode uint16) string {
+	x := (opcode & 0x0F00) >> 8
+	y := (opcode & 0x00F0) >> 4
+	n := opcode & 0x000F
+	nn := opcode & 0x00FF
+	nnn := opcode & 0x0FFF
+
+	switch mnemonic(opcode) {
+	case "00E0":
+		return "CLS"
+	case "00EE":
+		return "RET"
+	case "00FE":
+		return "LOW"
+	case "00FF":
+		return "HIGH"
+	case "0NNN":
+		return fmt.Sprintf("SYS %03X", nnn)
+	case "1NNN":
+		return fmt.Sprintf("JP %03X", nnn)
+	case "2NNN":
+		return fmt.Sprintf("CALL %03X", nnn)
+	case "3XNN":
+		return fmt.Sprintf("SE V%X, %02X", x, nn)
+	case "4XNN":
+		return fmt.Sprintf("SNE V%X, %02X", x, nn)
+	case "5XY0":
+		return fmt.Sprintf("SE V%X, V%X", x, y)
+	case "5XY2":
+		return fmt.Sprintf("SAVE V%X-V%X", x, y)
+	case "5XY3":
+		return fmt.Sprintf("LOAD V%X-V%X", x, y)
+	case "6XNN":
+		return fmt.Sprintf("LD V%X, %02X", x, nn)
+	case "7XNN":
+		return fmt.Sprintf("ADD V%X, %02X", x, nn)
+	case "8XY0":
+		return fmt.Sprintf("LD V%X, V%X", x, y)
+	case "8XY1":
+		return fmt.Sprintf("OR V%X, V%X", x, y)
+	case "8XY2":
+		return fmt.Sprintf("AND V%X, V%X", x, y)
+	case "8XY3":
+		return fmt.Sprintf("XOR V%X, V%X", x, y)
+	case "8XY4":
+		return fmt.Sprintf("ADD V%X, V%X", x, y)
+	case "8XY5":
+		return fmt.Sprintf("SUB V%X, V%X", x, y)
+	case "8XY6":
+		return fmt.Sprintf("SHR V%X, V%X", x, y)
+	case "8XY7":
+		return fmt.Sprintf("SUBN V%X, V%X", x, y)
+	case "8XYE":
+		return fmt.Sprintf("SHL V%X, V%X", x, y)
+	case "9XY0":
+		return fmt.Sprintf("SNE V%X, V%X", x, y)
+	case "ANNN":
+		return fmt.Sprintf("LD I, %03X", nnn)
+	case "BNNN":
+		return fmt.Sprintf("JP V0, %03X", nnn)
+	case "CXNN":
+		return fmt.Sprintf("RND V%X, %02X", x, nn)
+	case "DXYN":
+		return fmt.Sprintf("DRW V%X, V%X, %X", x, y, n)
+	case "EX9E":
+		return fmt.Sprintf("SKP V%X", x)
+	case "EXA1":
+		return fmt.Sprintf("SKNP V%X", x)
+	case "FN01":
+		return fmt.Sprintf("PLANE %X", x)
+	case "FX07":
+		return fmt.Sprintf("LD V%X, DT", x)
+	case "FX0A":
+		return fmt.Sprintf("LD V%X, K", x)
+	case "FX15":
+		return fmt.Sprintf("LD DT, V%X", x)
+	case "FX18":
+		return fmt.Sprintf("LD ST, V%X", x)
+	case "FX1E":
+		return fmt.Sprintf("ADD I, V%X", x)
+	case "FX29":
+		return fmt.Sprintf("LD F, V%X", x)
+	case "FX30":
+		return fmt.Sprintf("LD HF, V%X", x)
+	case "FX3A":
+		return fmt.Sprintf("PITCH V%X", x)
+	case "FX33":
+		return fmt.Sprintf("LD B, V%X", x)
+	case "FX55":
+		return fmt.Sprintf("LD [I], V%X", x)
+	case "FX65":
+		return fmt.Sprintf("LD V%X, [I]", x)
+	}
+	return fmt.Sprintf("DW %04X", opcode)
+}
+
+// Disassemble returns n lines of "addr: opcode  asm" starting at addr.
+func (c *Chip8) Disassemble(addr uint16, n int) []string {
+	var lines []string
+	for i := 0; i < n && int(addr)+1 < len(c.memory); i++ {
+		opcode := uint16(c.memory[addr])<<8 | uint16(c.memory[addr+1])
+		lines = append(lines, fmt.Sprintf("%03X: %04X  %s", addr, opcode, disassemble(opcode)))
+		addr += 2
+	}
+	return lines
+}
+
+// DisassembleProgram disassembles the loaded ROM from start to its end, or
+// to CodeLength with TrimPadding. An odd ROM's trailing byte is listed as DB.
+func (c *Chip8) DisassembleProgram(start uint16) []string {
+	end := c.programEnd()
+	if int(start) >= end {
+		return nil
+	}
+	lines := c.Disassemble(start, (end-int(start))/2)
+	if (end-int(start))%2 == 1 {
+		lines = append(lines, fmt.Sprintf("%03X: %02X    DB %02X", end-1, c.memory[end-1], c.memory[end-1]))
+	}
+	return lines
+}
+
+// programEnd is the address after the last ROM byte DisassembleProgram lists.
+func (c *Chip8) programEnd() int {
+	if c.TrimPadding {
+		return 0x200 + c.CodeLength()
+	}
+	return 0x200 + c.romLen
+}
+
+// DisassembleLabeled is DisassembleProgram with jump and call targets named:
+// each 1NNN, 2NNN and BNNN destination inside the listing gets an "L_NNN:"
+// line before it, and the instruction refers to it by that name. Targets
+// outside the listing, or not on an instruction boundary, stay as addresses.
+func (c *Chip8) DisassembleLabeled(start uint16) []string {
+	end := c.programEnd()
+	labels := map[uint16]string{}
+	for addr := int(start); addr+1 < end; addr += 2 {
+		opcode := uint16(c.memory[addr])<<8 | uint16(c.memory[addr+1])
+		switch mnemonic(opcode) {
+		case "1NNN", "2NNN", "BNNN":
+			target := opcode & 0x0FFF
+			if target >= start && int(target) < end && (target-start)%2 == 0 {
+				labels[target] = fmt.Sprintf("L_%03X", target)
+			}
+		}
+	}
+	var lines []string
+	for i, line := range c.DisassembleProgram(start) {
+		addr := start + uint16(i*2)
+		if label, ok := labels[addr]; ok {
+			lines = append(lines, label+":")
+		}
+		if int(addr)+1 < end {
+			opcode := uint16(c.memory[addr])<<8 | uint16(c.memory[addr+1])
+			switch mnemonic(opcode) {
+			case "1NNN", "2NNN", "BNNN":
+				if label, ok := labels[opcode&0x0FFF]; ok {
+					line = strings.TrimSuffix(line, fmt.Sprintf("%03X", opcode&0x0FFF)) + label
+				}
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// runDisasm implements "chip8 disasm [-start addr] [-labels] [-trim] <rom>".
+func runDisasm(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("disasm", flag.ContinueOnError)
+	fs.SetOutput(out)
+	start := fs.String("start", "200", "first address to disassemble, in hex")
+	labels := fs.Bool("labels", false, "name jump and call targets")
+	trim := fs.Bool("trim", false, "stop before trailing 0x00 or 0xFF padding")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: chip8 disasm [-start addr] [-labels] [-trim] <rom>")
+	}
+	addr, err := strconv.ParseUint(*start, 16, 12)
+	if err != nil {
+		return fmt.Errorf("bad start address %q", *start)
+	}
+
+	c := Chip8{TrimPadding: *trim}
+	c.Init()
+	if err := c.LoadROM(fs.Arg(0)); err != nil {
+		return err
+	}
+	lines := c.DisassembleProgram(uint16(addr))
+	if *labels {
+		lines = c.DisassembleLabeled(uint16(addr))
+	}
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(out, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
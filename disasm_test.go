@@ -0,0 +1,77 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunDisasmIBM(t *testing.T) {
+	rom := filepath.Join("assets", "roms", "ibm.ch8")
+	for _, tc := range []struct {
+		args []string
+		want []string
+	}{
+		{[]string{rom}, []string{
+			"200: 00E0  CLS",
+			"202: A22A  LD I, 22A",
+			"204: 600C  LD V0, 0C",
+			"206: 6108  LD V1, 08",
+			"208: D01F  DRW V0, V1, F",
+		}},
+		{[]string{"-start", "204", rom}, []string{
+			"204: 600C  LD V0, 0C",
+		}},
+	} {
+		var out strings.Builder
+		if err := runDisasm(tc.args, &out); err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(out.String(), "\n")
+		for i, want := range tc.want {
+			if lines[i] != want {
+				t.Errorf("%v: line %d = %q, want %q", tc.args, i, lines[i], want)
+			}
+		}
+	}
+}
+
+func TestRunDisasmUsage(t *testing.T) {
+	var out strings.Builder
+	for _, args := range [][]string{nil, {"-start", "zz", "rom.ch8"}} {
+		if err := runDisasm(args, &out); err == nil {
+			t.Errorf("runDisasm(%q) succeeded", args)
+		}
+	}
+}
+
+func TestDisassembleLabeled(t *testing.T) {
+	c := newTestChip(t, 0x2206, 0x1202, 0x1300, 0x00EE, 0xB203)
+	want := []string{
+		"200: 2206  CALL L_206",
+		"L_202:",
+		"202: 1202  JP L_202",
+		"204: 1300  JP 300", // outside the listing
+		"L_206:",
+		"206: 00EE  RET",
+		"208: B203  JP V0, 203", // not an instruction boundary
+	}
+	if got := c.DisassembleLabeled(0x200); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("listing:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestDisassembleTrimPadding(t *testing.T) {
+	for _, trim := range []bool{false, true} {
+		c := &Chip8{TrimPadding: trim}
+		c.Init()
+		loadProgram(t, c, append([]uint16{0x6001, 0x1202}, make([]uint16, 16)...)...)
+		want := 18
+		if trim {
+			want = 2
+		}
+		if got := len(c.DisassembleProgram(0x200)); got != want {
+			t.Errorf("TrimPadding=%v: %d lines, want %d", trim, got, want)
+		}
+	}
+}
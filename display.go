@@ -0,0 +1,299 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+)
+
+const sixelScale = 4
+
+// DrawMode selects how DXYN combines sprite pixels with the display.
+type DrawMode int
+
+const (
+	DrawXOR DrawMode = iota // standard: toggle pixels and report collisions in VF
+	DrawOR                  // only ever set pixels, VF is always 0; shows everything drawn
+)
+
+func (c *Chip8) width() int {
+	if c.hires {
+		return 128
+	}
+	return 64
+}
+
+func (c *Chip8) height() int {
+	if c.hires {
+		return 64
+	}
+	return 32
+}
+
+func (c *Chip8) pixel(x, y int) bool {
+	return c.display[x][y] != 0
+}
+
+// screen is the frame renderers read: the live display, or with
+// DoubleBuffer the one last passed to Present. Each render takes one screen
+// so it never mixes two frames.
+type screen struct {
+	c       *Chip8
+	display *[128][64]byte
+	prev    *[128][64]byte
+	hires   bool
+}
+
+// presentedFrame is what Present publishes for DoubleBuffer renderers.
+type presentedFrame struct {
+	display [128][64]byte
+	prev    [128][64]byte
+	hires   bool
+}
+
+func (c *Chip8) screen() screen {
+	if c.DoubleBuffer {
+		f := c.front.Load()
+		if f == nil {
+			f = &presentedFrame{}
+		}
+		return screen{c, &f.display, &f.prev, f.hires}
+	}
+	return screen{c, &c.display, &c.prevDisplay, c.hires}
+}
+
+// Present publishes the display to DoubleBuffer renderers. RunFrame calls it
+// when a frame ends; hosts driving Cycle themselves call it at their own
+// frame boundaries. It is safe to render from another goroutine meanwhile.
+func (c *Chip8) Present() {
+	f := &presentedFrame{display: c.display, hires: c.hires}
+	if old := c.front.Load(); old != nil {
+		f.prev = old.display
+	}
+	c.front.Store(f)
+}
+
+func (s screen) width() int {
+	if s.hires {
+		return 128
+	}
+	return 64
+}
+
+func (s screen) height() int {
+	if s.hires {
+		return 64
+	}
+	return 32
+}
+
+// planes is the plane bits renderers show for a pixel. With Ghosting an
+// unlit pixel keeps its value from the previous frame, which hides the
+// flicker of erase-and-redraw animation without touching emulation state.
+// Invert then swaps lit and unlit across the variant's planes.
+func (s screen) planes(x, y int) byte {
+	x, y = s.orient(x, y)
+	v := s.display[x][y] & 0x3
+	if s.c.Ghosting && v == 0 {
+		v = s.prev[x][y] & 0x3
+	}
+	if s.c.Invert {
+		v ^= byte(1<<s.c.PlaneCount() - 1)
+	}
+	return v
+}
+
+// orient maps a screen coordinate to the buffer pixel shown there under the
+// FlipX, FlipY and Rotate180 render options.
+func (s screen) orient(x, y int) (int, int) {
+	flipX, flipY := s.c.FlipX != s.c.Rotate180, s.c.FlipY != s.c.Rotate180
+	if flipX {
+		x = s.width() - 1 - x
+	}
+	if flipY {
+		y = s.height() - 1 - y
+	}
+	return x, y
+}
+
+func (s screen) lit(x, y int) bool {
+	return s.planes(x, y) != 0
+}
+
+func (c *Chip8) orient(x, y int) (int, int) {
+	return c.screen().orient(x, y)
+}
+
+func (c *Chip8) presented(x, y int) bool {
+	return c.screen().lit(x, y)
+}
+
+// ColorAt returns the palette colour renderers use for a pixel.
+func (c *Chip8) ColorAt(x, y int) color.RGBA {
+	return c.Palette[c.screen().planes(x, y)]
+}
+
+// Pixels returns the presented frame indexed [y][x].
+func (c *Chip8) Pixels() [][]bool {
+	s := c.screen()
+	rows := make([][]bool, s.height())
+	for y := range rows {
+		rows[y] = make([]bool, s.width())
+		for x := range rows[y] {
+			rows[y][x] = s.lit(x, y)
+		}
+	}
+	return rows
+}
+
+// clearDisplay zeroes the selected planes within the active resolution.
+func (c *Chip8) clearDisplay() {
+	for x := 0; x < c.width(); x++ {
+		for y := 0; y < c.height(); y++ {
+			c.display[x][y] &^= c.planes
+		}
+	}
+	c.markAllDirty()
+}
+
+func (c *Chip8) markDirty(x, y int) {
+	if !c.dirty {
+		c.dirty = true
+		c.dirtyMin, c.dirtyMax = image.Pt(x, y), image.Pt(x, y)
+		return
+	}
+	c.dirtyMin.X = min(c.dirtyMin.X, x)
+	c.dirtyMin.Y = min(c.dirtyMin.Y, y)
+	c.dirtyMax.X = max(c.dirtyMax.X, x)
+	c.dirtyMax.Y = max(c.dirtyMax.Y, y)
+}
+
+func (c *Chip8) markAllDirty() {
+	c.markDirty(0, 0)
+	c.markDirty(len(c.display)-1, len(c.display[0])-1)
+}
+
+// DirtyRect returns the smallest rectangle, in screen coordinates, holding
+// every pixel changed since the last call, and resets it. dirty is false
+// when nothing changed.
+func (c *Chip8) DirtyRect() (x, y, w, h int, dirty bool) {
+	if !c.dirty {
+		return 0, 0, 0, 0, false
+	}
+	c.dirty = false
+	r := image.Rectangle{c.dirtyMin, c.dirtyMax.Add(image.Pt(1, 1))}
+	r = r.Intersect(image.Rect(0, 0, c.width(), c.height()))
+	// map through the render orientation, which is its own inverse
+	x0, y0 := c.orient(r.Min.X, r.Min.Y)
+	x1, y1 := c.orient(r.Max.X-1, r.Max.Y-1)
+	r = image.Rect(x0, y0, x1, y1).Canon()
+	return r.Min.X, r.Min.Y, r.Dx() + 1, r.Dy() + 1, true
+}
+
+// DisplayString renders the display with one "█" per lit pixel.
+func (c *Chip8) DisplayString() string {
+	return c.textFrame("█", " ")
+}
+
+func (c *Chip8) textFrame(on, off string) string {
+	s := c.screen()
+	var b strings.Builder
+	for y := 0; y < s.height(); y++ {
+		for x := 0; x < s.width(); x++ {
+			if s.lit(x, y) {
+				b.WriteString(on)
+			} else {
+				b.WriteString(off)
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// ExportFrame writes the current frame as "ascii" ('#' and '.'), "unicode"
+// (same as DisplayString) or "sixel" for terminals with sixel graphics.
+func (c *Chip8) ExportFrame(w io.Writer, format string) error {
+	var err error
+	switch format {
+	case "ascii":
+		_, err = io.WriteString(w, c.textFrame("#", "."))
+	case "unicode":
+		_, err = io.WriteString(w, c.DisplayString())
+	case "sixel":
+		_, err = io.WriteString(w, c.sixelFrame())
+	default:
+		return fmt.Errorf("unknown frame format: %s", format)
+	}
+	return err
+}
+
+// sixelFrame encodes the display as a two-colour sixel image, each CHIP-8
+// pixel scaled to a sixelScale square.
+func (c *Chip8) sixelFrame() string {
+	s := c.screen()
+	width, height := s.width()*sixelScale, s.height()*sixelScale
+	var b strings.Builder
+	b.WriteString("\x1bPq")
+	fmt.Fprintf(&b, "\"1;1;%d;%d", width, height)
+	b.WriteString("#0;2;0;0;0#1;2;100;100;100")
+	for band := 0; band < height; band += 6 {
+		for color := 0; color < 2; color++ {
+			fmt.Fprintf(&b, "#%d", color)
+			run, last := 0, byte(0)
+			for px := 0; px < width; px++ {
+				var bits byte
+				for i := 0; i < 6 && band+i < height; i++ {
+					lit := s.lit(px/sixelScale, (band+i)/sixelScale)
+					if lit == (color == 1) {
+						bits |= 1 << i
+					}
+				}
+				ch := '?' + bits
+				if run > 0 && ch != last {
+					writeSixelRun(&b, last, run)
+					run = 0
+				}
+				last = ch
+				run++
+			}
+			writeSixelRun(&b, last, run)
+			if color == 0 {
+				b.WriteByte('$')
+			}
+		}
+		b.WriteByte('-')
+	}
+	b.WriteString("\x1b\\")
+	return b.String()
+}
+
+func writeSixelRun(b *strings.Builder, ch byte, n int) {
+	if n > 3 {
+		fmt.Fprintf(b, "!%d%c", n, ch)
+		return
+	}
+	for i := 0; i < n; i++ {
+		b.WriteByte(ch)
+	}
+}
+
+func (c *Chip8) DisplayEqual(other *Chip8) bool {
+	return c.display == other.display
+}
+
+// DisplayDiff returns the coordinates of every pixel that differs between the
+// two displays.
+func (c *Chip8) DisplayDiff(other *Chip8) []image.Point {
+	var diff []image.Point
+	for y := 0; y < len(c.display[0]); y++ {
+		for x := 0; x < len(c.display); x++ {
+			if c.display[x][y] != other.display[x][y] {
+				diff = append(diff, image.Pt(x, y))
+			}
+		}
+	}
+	return diff
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"strings"
+	"testing"
+)
+
+// goldenFrame draws a 4x2 box in the top-left corner and returns the
+// expected text rendering with on and off as the pixel glyphs.
+func goldenFrame(t *testing.T, on, off string) (*Chip8, string) {
+	t.Helper()
+	c := newTestChip(t)
+	drawAt(t, c, 0, 0, 0xF0, 0x90)
+	rows := []string{
+		strings.Repeat(on, 4) + strings.Repeat(off, 60),
+		on + off + off + on + strings.Repeat(off, 60),
+	}
+	for len(rows) < 32 {
+		rows = append(rows, strings.Repeat(off, 64))
+	}
+	return c, strings.Join(rows, "\n") + "\n"
+}
+
+func TestExportFrameText(t *testing.T) {
+	for _, tc := range []struct{ format, on, off string }{
+		{"ascii", "#", "."},
+		{"unicode", "█", " "},
+	} {
+		c, want := goldenFrame(t, tc.on, tc.off)
+		var buf bytes.Buffer
+		if err := c.ExportFrame(&buf, tc.format); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != want {
+			t.Errorf("%s frame:\n%s\nwant:\n%s", tc.format, buf.String(), want)
+		}
+	}
+}
+
+func TestExportFrameSixel(t *testing.T) {
+	c, _ := goldenFrame(t, "#", ".")
+	var buf bytes.Buffer
+	if err := c.ExportFrame(&buf, "sixel"); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "\x1bPq\"1;1;256;128") || !strings.HasSuffix(out, "\x1b\\") {
+		t.Errorf("sixel output lacks the DCS envelope: %q...", out[:min(len(out), 20)])
+	}
+	if strings.Count(out, "-") != 128/6+1 {
+		t.Errorf("sixel output has %d bands, want %d", strings.Count(out, "-"), 128/6+1)
+	}
+}
+
+func TestExportFrameUnknownFormat(t *testing.T) {
+	c := newTestChip(t)
+	if err := c.ExportFrame(&bytes.Buffer{}, "braille"); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
+
+func TestDisplayEqualAcrossConfigs(t *testing.T) {
+	run := func(q Quirks) *Chip8 {
+		c := &Chip8{}
+		c.SetQuirks(q)
+		c.Init()
+		if err := c.LoadEmbeddedROM("ibm"); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 200; i++ {
+			if err := c.Cycle(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return c
+	}
+	a, b := run(DefaultQuirks), run(CosmacQuirks)
+	if !a.DisplayEqual(b) {
+		t.Fatalf("IBM logo differs between quirk sets at %v", a.DisplayDiff(b))
+	}
+	if diff := a.DisplayDiff(b); len(diff) != 0 {
+		t.Errorf("DisplayDiff = %v for equal displays", diff)
+	}
+	drawAt(t, b, 0, 0, 0xC0)
+	if a.DisplayEqual(b) {
+		t.Error("DisplayEqual after drawing on one machine")
+	}
+	if diff := a.DisplayDiff(b); len(diff) != 2 || diff[0] != image.Pt(0, 0) || diff[1] != image.Pt(1, 0) {
+		t.Errorf("DisplayDiff = %v, want [(0,0) (1,0)]", diff)
+	}
+}
+
+func TestClearDisplayModes(t *testing.T) {
+	t.Run("lores", func(t *testing.T) {
+		c := newTestChip(t, 0x00E0)
+		c.display[10][5], c.display[100][50] = 1, 1
+		step(t, c, 1)
+		if c.display[10][5] != 0 || c.display[100][50] != 1 {
+			t.Errorf("lores 00E0 left (10,5)=%d (100,50)=%d, want 0 and 1", c.display[10][5], c.display[100][50])
+		}
+	})
+	t.Run("hires", func(t *testing.T) {
+		c := newTestChip(t, 0x00FF, 0x00E0)
+		c.Variant = VariantSCHIP
+		step(t, c, 1)
+		c.display[10][5], c.display[127][63] = 1, 1
+		step(t, c, 1)
+		if c.display != ([128][64]byte{}) {
+			t.Error("hires 00E0 left pixels lit")
+		}
+	})
+	t.Run("planes", func(t *testing.T) {
+		c := newTestChip(t, 0xF201, 0x00E0)
+		c.Variant = VariantXOChip
+		c.display[10][5], c.display[11][5] = 3, 2
+		step(t, c, 2)
+		if c.display[10][5] != 1 || c.display[11][5] != 0 {
+			t.Errorf("plane 2 00E0 left (10,5)=%d (11,5)=%d, want 1 and 0", c.display[10][5], c.display[11][5])
+		}
+	})
+}
+
+func TestGhostingCombinesFrames(t *testing.T) {
+	// Frame 1 draws a pixel at (0,0); frame 2 erases it and draws one at (8,0).
+	for _, ghosting := range []bool{false, true} {
+		c := newTestChip(t, 0x6000, 0xA300, 0xD011, 0xD011, 0x7008, 0xD011)
+		c.memory[0x300] = 0x80
+		c.InstructionsPerFrame = 3
+		c.Ghosting = ghosting
+		for i := 0; i < 2; i++ {
+			if err := c.RunFrame(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if c.pixel(0, 0) {
+			t.Fatal("emulated display kept the erased pixel")
+		}
+		px := c.Pixels()
+		if !px[0][8] || px[0][0] != ghosting {
+			t.Errorf("Ghosting=%v: presented (0,0)=%v (8,0)=%v", ghosting, px[0][0], px[0][8])
+		}
+	}
+}
+
+func TestFlipAndRotate(t *testing.T) {
+	// An L in the top-left corner: (0,0), (0,1) and (1,1).
+	for _, tc := range []struct {
+		name                 string
+		flipX, flipY, rotate bool
+		want                 []image.Point
+	}{
+		{"native", false, false, false, []image.Point{{0, 0}, {0, 1}, {1, 1}}},
+		{"flip x", true, false, false, []image.Point{{63, 0}, {63, 1}, {62, 1}}},
+		{"flip y", false, true, false, []image.Point{{0, 31}, {0, 30}, {1, 30}}},
+		{"rotate 180", false, false, true, []image.Point{{63, 31}, {63, 30}, {62, 30}}},
+		{"flip x and rotate", true, false, true, []image.Point{{0, 31}, {0, 30}, {1, 30}}},
+	} {
+		c := newTestChip(t)
+		drawAt(t, c, 0, 0, 0x80, 0xC0)
+		c.FlipX, c.FlipY, c.Rotate180 = tc.flipX, tc.flipY, tc.rotate
+		var lit []image.Point
+		for y, row := range c.Pixels() {
+			for x, on := range row {
+				if on {
+					lit = append(lit, image.Pt(x, y))
+				}
+			}
+		}
+		if len(lit) != len(tc.want) {
+			t.Errorf("%s: lit %v, want %v", tc.name, lit, tc.want)
+			continue
+		}
+		for _, p := range tc.want {
+			if !c.Pixels()[p.Y][p.X] {
+				t.Errorf("%s: %v not lit, lit %v", tc.name, p, lit)
+			}
+		}
+		if !c.pixel(0, 0) || c.pixel(63, 31) {
+			t.Errorf("%s: the option changed the emulated display", tc.name)
+		}
+	}
+}
+
+func TestInvert(t *testing.T) {
+	for _, invert := range []bool{false, true} {
+		c := newTestChip(t)
+		drawAt(t, c, 2, 3, 0x80)
+		c.Invert = invert
+		px := c.Pixels()
+		if px[3][2] == invert || px[0][0] != invert {
+			t.Errorf("Invert=%v: sprite pixel %v, background %v", invert, px[3][2], px[0][0])
+		}
+		if !c.pixel(2, 3) || c.pixel(0, 0) {
+			t.Errorf("Invert=%v changed the emulated display", invert)
+		}
+		if got := strings.Count(c.DisplayString(), "█"); (invert && got != 64*32-1) || (!invert && got != 1) {
+			t.Errorf("Invert=%v: DisplayString has %d lit pixels", invert, got)
+		}
+	}
+}
+
+func TestDirtyRect(t *testing.T) {
+	c := newTestChip(t)
+	c.DirtyRect() // drop whatever Init marked
+	drawAt(t, c, 60, 30, 0xF0, 0x90)
+	if x, y, w, h, dirty := c.DirtyRect(); !dirty || x != 60 || y != 30 || w != 4 || h != 2 {
+		t.Errorf("after a corner sprite DirtyRect = %d,%d %dx%d %v, want 60,30 4x2", x, y, w, h, dirty)
+	}
+	if _, _, _, _, dirty := c.DirtyRect(); dirty {
+		t.Error("DirtyRect not reset after it was read")
+	}
+	if err := c.ExecuteAll(0x00E0); err != nil {
+		t.Fatal(err)
+	}
+	if x, y, w, h, dirty := c.DirtyRect(); !dirty || x != 0 || y != 0 || w != 64 || h != 32 {
+		t.Errorf("after 00E0 DirtyRect = %d,%d %dx%d %v, want the whole 64x32 screen", x, y, w, h, dirty)
+	}
+}
+
+func TestDoubleBufferShowsPresentedFrame(t *testing.T) {
+	c := newTestChip(t, 0xA300, 0xD011, 0x1204)
+	c.memory[0x300] = 0x80
+	c.DoubleBuffer = true
+	drawAt(t, c, 5, 5, 0x80)
+	c.V[0], c.V[1] = 0, 0
+	if c.Pixels()[5][5] {
+		t.Fatal("draw visible before Present")
+	}
+	c.Present()
+	if !c.Pixels()[5][5] {
+		t.Fatal("draw not visible after Present")
+	}
+	if err := c.RunFrame(); err != nil {
+		t.Fatal(err)
+	}
+	if px := c.Pixels(); !px[0][0] || !px[5][5] {
+		t.Error("RunFrame did not present the finished frame")
+	}
+}
+
+// TestDoubleBufferConcurrentRender renders from another goroutine while
+// frames run, as a GUI would; run it with -race.
+func TestDoubleBufferConcurrentRender(t *testing.T) {
+	c := newTestChip(t, 0xA300, 0xD011, 0x7001, 0x1202)
+	c.memory[0x300] = 0x80
+	c.DoubleBuffer = true
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			c.DisplayString()
+			c.Pixels()
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if err := c.RunFrame(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+}
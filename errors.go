@@ -0,0 +1,53 @@
+package main
+
+import "fmt"
+
+// ErrUnknownOpcode is returned in strict mode when an opcode is not
+// recognised. PC is the address the opcode was fetched from.
+type ErrUnknownOpcode struct {
+	PC     uint16
+	Opcode uint16
+	Disasm string
+}
+
+func (e *ErrUnknownOpcode) Error() string {
+	return fmt.Sprintf("unknown opcode %04X at %03X (%s)", e.Opcode, e.PC, e.Disasm)
+}
+
+func (c *Chip8) unknownOpcode(opcode uint16) error {
+	if c.Strict {
+		return &ErrUnknownOpcode{PC: c.opPC, Opcode: opcode, Disasm: disassemble(opcode)}
+	}
+	c.logf("Unknown opcode: %04X at %03X", opcode, c.opPC)
+	return nil
+}
+
+// fault marks the machine faulted at the current instruction and returns the
+// error describing why.
+func (c *Chip8) fault(format string, args ...any) error {
+	c.Faulted = true
+	c.faultPC = c.opPC
+	return fmt.Errorf("%03X: "+format, append([]any{c.opPC}, args...)...)
+}
+
+// RetryLast rewinds PC to the faulting instruction and executes it again,
+// e.g. after the host has corrected I.
+func (c *Chip8) RetryLast() error {
+	if !c.Faulted {
+		return fmt.Errorf("no faulted instruction to retry")
+	}
+	c.Faulted = false
+	c.PC = c.faultPC
+	return c.Step()
+}
+
+// SafeCycle runs Cycle and converts any panic into an error carrying the
+// panic value.
+func (c *Chip8) SafeCycle() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic at %03X: %v", c.opPC, r)
+		}
+	}()
+	return c.Cycle()
+}
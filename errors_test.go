@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRetryLastAfterFault(t *testing.T) {
+	c := newTestChip(t, 0xAFFF, 0xF155) // store V0-V1 at 0xFFF, past the end
+	step(t, c, 1)
+	if err := c.Step(); err == nil || !c.Faulted {
+		t.Fatalf("FX55 past memory: err=%v Faulted=%v", err, c.Faulted)
+	}
+	c.I = 0x300
+	if err := c.RetryLast(); err != nil {
+		t.Fatal(err)
+	}
+	if c.PC != 0x204 || c.Faulted {
+		t.Errorf("after retry PC=%03X Faulted=%v", c.PC, c.Faulted)
+	}
+}
+
+func TestResetClearsFault(t *testing.T) {
+	c := newTestChip(t, 0xAFFF, 0xF155)
+	step(t, c, 1)
+	c.Step()
+	c.Reset()
+	if c.Faulted {
+		t.Error("Faulted still set after Reset")
+	}
+	if err := c.RetryLast(); err == nil {
+		t.Errorf("RetryLast after Reset succeeded, PC=%03X", c.PC)
+	}
+}
+
+func TestStrictUnknownOpcode(t *testing.T) {
+	c := newTestChip(t, 0x6000, 0x5121)
+	c.Strict = true
+	step(t, c, 1)
+	err := c.Step()
+	var unknown *ErrUnknownOpcode
+	if !errors.As(err, &unknown) {
+		t.Fatalf("err = %v, want *ErrUnknownOpcode", err)
+	}
+	if unknown.PC != 0x202 || unknown.Opcode != 0x5121 || unknown.Disasm != "DW 5121" {
+		t.Errorf("error fields = %+v", *unknown)
+	}
+}
+
+func TestLenientUnknownOpcodeSkips(t *testing.T) {
+	c := newTestChip(t, 0x5121, 0x6007)
+	c.Logger = NopLogger
+	step(t, c, 2)
+	if c.V[0] != 7 {
+		t.Errorf("execution did not continue past the unknown opcode")
+	}
+}
+
+func TestSafeCycleRecoversPanic(t *testing.T) {
+	c := newTestChip(t, 0x6001, 0x8014)
+	c.OnFlagWrite = func(uint16, byte, byte, string) { panic("hook exploded") }
+	if err := c.SafeCycle(); err != nil {
+		t.Fatal(err)
+	}
+	err := c.SafeCycle()
+	if err == nil || !strings.Contains(err.Error(), "hook exploded") || !strings.Contains(err.Error(), "202") {
+		t.Errorf("SafeCycle = %v, want the panic message at 202", err)
+	}
+}
+
+func TestSafeCyclePassesErrors(t *testing.T) {
+	c := newTestChip(t)
+	c.PC = 0xFFF
+	if err := c.SafeCycle(); err == nil {
+		t.Error("SafeCycle with PC out of bounds returned nil")
+	}
+}
+
+func TestMachineCodeCall(t *testing.T) {
+	c := newTestChip(t, 0x0123, 0x6001)
+	step(t, c, 2)
+	if c.V[0] != 1 || c.Faulted {
+		t.Errorf("lenient 0NNN: V0=%d Faulted=%v, want the call skipped", c.V[0], c.Faulted)
+	}
+
+	c = newTestChip(t, 0x0123)
+	c.Strict = true
+	err := c.Step()
+	if want := "200: machine code call 0123 not supported"; err == nil || err.Error() != want {
+		t.Errorf("strict 0NNN: err = %v, want %q", err, want)
+	}
+}
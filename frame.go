@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// RunFrame executes one 60Hz frame worth of instructions. When
+// MaxDrawsPerFrame is set, the frame ends early after that many DXYN draws so
+// the renderer can present them. With AccurateTiming the frame is a budget of
+// ClockHz/60 machine cycles charged per instruction by cycleCost, and any
+// overrun is carried into the next frame.
+//
+// With the DisplayWait quirk the first DXYN of a frame draws immediately and
+// execution carries on with the rest of the frame's budget, but a second
+// DXYN ends the frame and is executed at the start of the next one, so a ROM
+// draws at most once per 60Hz frame.
+//
+// Keys queued with QueueKey are applied before the first instruction, and
+// screenshots from RequestScreenshot are taken after the last, once the
+// frame has been passed to Present when DoubleBuffer is set. With FrameAudio
+// each frame also produces one frame of beeper samples.
+//
+// The whole frame holds the lock Step takes, so State and the other debugger
+// accessors only ever see the machine between frames.
+func (c *Chip8) RunFrame() error {
+	c.applyQueuedKeys()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer c.stepPhosphor()
+	if c.FrameAudio {
+		defer c.generateFrameAudio()
+	}
+	defer c.deliverScreenshots()
+	if c.DoubleBuffer {
+		defer c.Present()
+	}
+	c.prevDisplay = c.display
+	c.drawsThisFrame = 0
+	c.captureFrame()
+	if c.AccurateTiming {
+		return c.runTimedFrame()
+	}
+	for i := 0; i < c.InstructionsPerFrame; i++ {
+		if c.waitForVBlank() {
+			break
+		}
+		if err := c.Cycle(); err != nil {
+			return err
+		}
+		if c.MaxDrawsPerFrame > 0 && c.drawsThisFrame >= c.MaxDrawsPerFrame {
+			break
+		}
+	}
+	return nil
+}
+
+func (c *Chip8) runTimedFrame() error {
+	clock := c.ClockHz
+	if clock <= 0 {
+		clock = vipClockHz
+	}
+	c.cycleBudget += clock / 60
+	for c.cycleBudget > 0 {
+		if c.waitForVBlank() {
+			c.cycleBudget = 0
+			break
+		}
+		cost := cycleCost(c.Peek())
+		if err := c.Cycle(); err != nil {
+			return err
+		}
+		c.cycleBudget -= cost
+		if c.MaxDrawsPerFrame > 0 && c.drawsThisFrame >= c.MaxDrawsPerFrame {
+			break
+		}
+	}
+	return nil
+}
+
+func (c *Chip8) waitForVBlank() bool {
+	return c.Quirks.DisplayWait && c.drawsThisFrame > 0 && c.Peek()&0xF000 == 0xD000
+}
+
+// RunContext runs frames at 60Hz, ticking the timers itself, until ctx is
+// cancelled or an instruction fails. Do not combine it with StartTimers. On
+// cancellation the beeper is stopped and ctx.Err() is returned.
+func (c *Chip8) RunContext(ctx context.Context) error {
+	ticker := time.NewTicker(time.Second / 60)
+	defer ticker.Stop()
+	defer c.stopBeep()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := c.RunFrame(); err != nil {
+				return err
+			}
+			c.TickFrame()
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMaxDrawsPerFrame(t *testing.T) {
+	program := make([]uint16, 10)
+	for i := range program {
+		program[i] = 0xD011
+	}
+	for _, tc := range []struct {
+		max    int
+		wantPC []uint16 // after each frame
+	}{
+		{0, []uint16{0x214}},
+		{3, []uint16{0x206, 0x20C}},
+		{4, []uint16{0x208, 0x210}},
+	} {
+		c := newTestChip(t, program...)
+		c.InstructionsPerFrame = 10
+		c.MaxDrawsPerFrame = tc.max
+		for frame, want := range tc.wantPC {
+			if err := c.RunFrame(); err != nil {
+				t.Fatal(err)
+			}
+			if c.PC != want {
+				t.Errorf("max %d: frame %d ended at %03X, want %03X", tc.max, frame, c.PC, want)
+			}
+		}
+	}
+}
+
+func TestRunContextCancel(t *testing.T) {
+	c := newTestChip(t, 0x1200)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- c.RunContext(ctx) }()
+	deadline := time.After(5 * time.Second)
+	for c.frames.Load() < 3 {
+		select {
+		case err := <-done:
+			t.Fatalf("RunContext returned early: %v", err)
+		case <-deadline:
+			t.Fatal("RunContext ran no frames")
+		case <-time.After(time.Millisecond):
+		}
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("RunContext = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("RunContext did not return after cancel")
+	}
+}
+
+func TestDisplayWaitOneDrawPerFrame(t *testing.T) {
+	c := newTestChip(t, 0xD011, 0x6105, 0xD011, 0x6206, 0x1208)
+	c.Quirks.DisplayWait = true
+	if err := c.RunFrame(); err != nil {
+		t.Fatal(err)
+	}
+	if c.PC != 0x204 || c.V[1] != 5 || c.DrawStats().Sprites != 1 {
+		t.Fatalf("first frame ended at %03X with V1=%d after %d draws, want 204, 5 and 1",
+			c.PC, c.V[1], c.DrawStats().Sprites)
+	}
+	if err := c.RunFrame(); err != nil {
+		t.Fatal(err)
+	}
+	if c.DrawStats().Sprites != 2 || c.V[2] != 6 || c.PC != 0x208 {
+		t.Errorf("second frame: %d draws, V2=%d, PC=%03X, want 2, 6 and 208",
+			c.DrawStats().Sprites, c.V[2], c.PC)
+	}
+}
+
+func TestNoDisplayWaitDrawsFreely(t *testing.T) {
+	c := newTestChip(t, 0xD011, 0x6105, 0xD011, 0x6206, 0x1208)
+	c.Quirks.DisplayWait = false
+	if err := c.RunFrame(); err != nil {
+		t.Fatal(err)
+	}
+	if c.DrawStats().Sprites != 2 || c.V[2] != 6 {
+		t.Errorf("frame drew %d sprites with V2=%d, want 2 and 6", c.DrawStats().Sprites, c.V[2])
+	}
+}
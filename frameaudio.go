@@ -0,0 +1,79 @@
+package main
+
+import (
+	"math"
+	"sync"
+
+	"github.com/faiface/beep"
+)
+
+// frameAudioFrames is how many frames of samples frameAudio holds before it
+// drops the oldest, bounding latency when the speaker falls behind.
+const frameAudioFrames = 8
+
+// frameAudio buffers beeper samples produced by RunFrame for the speaker
+// goroutine to drain.
+type frameAudio struct {
+	mu       sync.Mutex
+	samples  [][2]float64
+	produced uint64
+	phase    float64
+}
+
+func (a *frameAudio) reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.samples = nil
+	a.produced = 0
+	a.phase = 0
+}
+
+// generateFrameAudio appends one 60Hz frame of the square wave, or silence
+// when the beeper is off. Pitch depends only on the sample count per frame,
+// so running frames faster or slower than 60Hz does not change it.
+func (c *Chip8) generateFrameAudio() {
+	n := int(sampleRate) / 60
+	beeping := c.Beeping()
+	step := currentBeepFrequency() / float64(sampleRate)
+
+	a := &c.audio
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for range n {
+		v := 0.0
+		if beeping {
+			a.phase = math.Mod(a.phase+step, 1)
+			v = 0.5
+			if a.phase >= 0.5 {
+				v = -0.5
+			}
+		}
+		a.samples = append(a.samples, [2]float64{v, v})
+	}
+	a.produced += uint64(n)
+	if over := len(a.samples) - frameAudioFrames*n; over > 0 {
+		a.samples = append(a.samples[:0], a.samples[over:]...)
+	}
+}
+
+// AudioStream returns a streamer that plays the samples RunFrame generates
+// with FrameAudio set, and silence when none are buffered, so a paused
+// machine goes quiet at once. Play it once, e.g. speaker.Play(c.AudioStream()).
+func (c *Chip8) AudioStream() beep.Streamer {
+	return beep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+		a := &c.audio
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		n := copy(samples, a.samples)
+		a.samples = append(a.samples[:0], a.samples[n:]...)
+		clear(samples[n:])
+		return len(samples), true
+	})
+}
+
+// AudioSamples returns how many samples FrameAudio has generated since Reset.
+func (c *Chip8) AudioSamples() uint64 {
+	c.audio.mu.Lock()
+	defer c.audio.mu.Unlock()
+	return c.audio.produced
+}
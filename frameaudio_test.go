@@ -0,0 +1,54 @@
+package main
+
+import "testing"
+
+func TestFrameAudioOnlyFromRunFrame(t *testing.T) {
+	c := newTestChip(t, 0x1200)
+	c.FrameAudio = true
+	step(t, c, 100)
+	c.TickFrame()
+	if n := c.AudioSamples(); n != 0 {
+		t.Fatalf("%d samples without RunFrame", n)
+	}
+	buf := make([][2]float64, 10)
+	buf[0] = [2]float64{1, 1}
+	c.AudioStream().Stream(buf)
+	if buf[0] != ([2]float64{}) {
+		t.Error("AudioStream played stale data with nothing buffered")
+	}
+	for i := 0; i < 3; i++ {
+		if err := c.RunFrame(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, want := c.AudioSamples(), uint64(3*int(sampleRate)/60); n != want {
+		t.Errorf("%d samples after 3 frames, want %d", n, want)
+	}
+}
+
+func TestFrameAudioBeepAndRingLimit(t *testing.T) {
+	c := newTestChip(t, 0x1200)
+	c.FrameAudio = true
+	c.SetSoundTimer(255)
+	for i := 0; i < frameAudioFrames+4; i++ {
+		if err := c.RunFrame(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	perFrame := int(sampleRate) / 60
+	buf := make([][2]float64, (frameAudioFrames+1)*perFrame)
+	c.AudioStream().Stream(buf)
+	loud := 0
+	for _, s := range buf {
+		if s[0] != 0 {
+			loud++
+		}
+	}
+	if loud != frameAudioFrames*perFrame {
+		t.Errorf("%d audible samples buffered, want the ring's %d", loud, frameAudioFrames*perFrame)
+	}
+	c.Reset()
+	if c.AudioSamples() != 0 {
+		t.Errorf("AudioSamples = %d after Reset", c.AudioSamples())
+	}
+}
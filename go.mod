@@ -2,8 +2,9 @@ module github.com/conorkenn/chip8
 
 go 1.22.0
 
+require github.com/faiface/beep v1.1.0
+
 require (
-	github.com/faiface/beep v1.1.0 // indirect
 	github.com/hajimehoshi/oto v1.0.1 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	golang.org/x/exp v0.0.0-20190306152737-a1d7652674e8 // indirect
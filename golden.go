@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Golden runs play every embedded ROM for a fixed number of frames under the
+// default quirks and a fixed seed, so any change in behaviour shows up as a
+// different StateHash.
+const (
+	goldenFrames = 120
+	goldenSeed   = 1
+)
+
+// StateHash returns a hex SHA-1 of the registers, stack, timers, display and
+// memory.
+func (c *Chip8) StateHash() string {
+	h := sha1.New()
+	var regs [8]byte
+	binary.BigEndian.PutUint16(regs[0:], c.PC)
+	binary.BigEndian.PutUint16(regs[2:], c.I)
+	regs[4] = c.SP
+	regs[5] = c.DelayTimer()
+	regs[6] = c.SoundTimer()
+	if c.hires {
+		regs[7] = 1
+	}
+	h.Write(regs[:])
+	h.Write(c.V[:])
+	binary.Write(h, binary.BigEndian, c.stack)
+	for x := range c.display {
+		h.Write(c.display[x][:])
+	}
+	h.Write(c.memory[:])
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func goldenHash(name string) (string, error) {
+	c := Chip8{}
+	c.Init()
+	c.SetSeed(goldenSeed)
+	if err := c.LoadEmbeddedROM(name); err != nil {
+		return "", err
+	}
+	for i := 0; i < goldenFrames; i++ {
+		if err := c.RunFrame(); err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+		c.TickFrame()
+	}
+	return c.StateHash(), nil
+}
+
+// WriteGolden records "name hash" lines for every embedded ROM.
+func WriteGolden(w io.Writer) error {
+	for _, name := range ListEmbeddedROMs() {
+		sum, err := goldenHash(name)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s %s\n", name, sum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VerifyGolden re-runs the ROMs listed by WriteGolden and reports any whose
+// hash has drifted.
+func VerifyGolden(r io.Reader) error {
+	var drift []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return fmt.Errorf("bad golden line: %q", scanner.Text())
+		}
+		sum, err := goldenHash(fields[0])
+		if err != nil {
+			return err
+		}
+		if sum != fields[1] {
+			drift = append(drift, fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(drift) > 0 {
+		return fmt.Errorf("golden hash mismatch: %s", strings.Join(drift, ", "))
+	}
+	return nil
+}
+
+// runGolden implements "chip8 golden [-update] <file>".
+func runGolden(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("golden", flag.ContinueOnError)
+	fs.SetOutput(out)
+	update := fs.Bool("update", false, "rewrite the golden file from the current build")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: chip8 golden [-update] <file>")
+	}
+	if *update {
+		f, err := os.Create(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		if err := WriteGolden(f); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := VerifyGolden(f); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "ok")
+	return nil
+}
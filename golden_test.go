@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGolden fails when any embedded ROM's final state drifts from
+// testdata/golden.txt. After an intended behaviour change, regenerate it with
+// "go run . golden -update testdata/golden.txt".
+func TestGolden(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "golden.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyGolden(strings.NewReader(string(data))); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range ListEmbeddedROMs() {
+		if !strings.Contains(string(data), name+" ") {
+			t.Errorf("%s has no golden hash", name)
+		}
+	}
+}
+
+func TestVerifyGoldenDetectsDrift(t *testing.T) {
+	err := VerifyGolden(strings.NewReader("ibm 0000000000000000000000000000000000000000\n"))
+	if err == nil || !strings.Contains(err.Error(), "ibm") {
+		t.Errorf("VerifyGolden = %v, want a mismatch naming ibm", err)
+	}
+	if err := VerifyGolden(strings.NewReader("ibm\n")); err == nil {
+		t.Error("VerifyGolden accepted a line without a hash")
+	}
+}
+
+func TestStateHash(t *testing.T) {
+	a, b := newTestChip(t, 0x6001), newTestChip(t, 0x6001)
+	if a.StateHash() != b.StateHash() {
+		t.Fatal("identical machines hash differently")
+	}
+	step(t, b, 1)
+	if a.StateHash() == b.StateHash() {
+		t.Error("StateHash unchanged after an instruction")
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+const defaultIdleWindow = 64
+
+// trackIdle hashes the state after each instruction and flags the machine as
+// idle when the same state recurs within the last IdleWindow instructions:
+// without input, timers or a draw changing anything it is looping in place,
+// e.g. polling a key.
+func (c *Chip8) trackIdle() {
+	window := c.IdleWindow
+	if window <= 0 {
+		window = defaultIdleWindow
+	}
+	if c.idleSeen == nil {
+		c.idleSeen = make(map[uint64]int)
+	}
+
+	h := fnv.New64a()
+	var buf [8]byte
+	binary.BigEndian.PutUint16(buf[0:], c.PC)
+	binary.BigEndian.PutUint16(buf[2:], c.I)
+	buf[4] = c.SP
+	h.Write(buf[:5])
+	h.Write(c.V[:])
+	binary.BigEndian.PutUint64(buf[:], c.drawStats.Sprites)
+	h.Write(buf[:])
+	sum := h.Sum64()
+
+	c.idle = c.idleSeen[sum] > 0
+	c.idleRing = append(c.idleRing, sum)
+	c.idleSeen[sum]++
+	for len(c.idleRing) > window {
+		old := c.idleRing[0]
+		c.idleRing = c.idleRing[1:]
+		if c.idleSeen[old]--; c.idleSeen[old] == 0 {
+			delete(c.idleSeen, old)
+		}
+	}
+}
+
+// SuspectedIdle reports whether DetectIdle has seen the machine loop without
+// visible progress, so a host can show "waiting for input" rather than "hung".
+func (c *Chip8) SuspectedIdle() bool {
+	return c.idle
+}
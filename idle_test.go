@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestDetectIdleKeyWaitLoop(t *testing.T) {
+	// Poll key 5 and jump back until it is pressed.
+	c := newTestChip(t, 0x6005, 0xE09E, 0x1202, 0x6101, 0x1208)
+	c.DetectIdle = true
+	step(t, c, 1)
+	if c.SuspectedIdle() {
+		t.Fatal("idle before the loop started")
+	}
+	step(t, c, 6)
+	if !c.SuspectedIdle() {
+		t.Fatal("key-wait loop not flagged idle")
+	}
+	c.SetKey(5, true)
+	step(t, c, 2)
+	if c.V[1] != 1 || c.SuspectedIdle() {
+		t.Errorf("after the key press V1=%d idle=%v, want 1 and false", c.V[1], c.SuspectedIdle())
+	}
+}
+
+func TestDetectIdleCountingLoop(t *testing.T) {
+	c := newTestChip(t, 0x7001, 0x1200) // V0 changes every pass
+	c.DetectIdle = true
+	for i := 0; i < 100; i++ {
+		step(t, c, 1)
+		if c.SuspectedIdle() {
+			t.Fatalf("counting loop flagged idle at step %d", i)
+		}
+	}
+}
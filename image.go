@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+)
+
+// DefaultPalette colours the four XO-CHIP plane combinations: off, plane 1,
+// plane 2 and both. Classic ROMs only use the first two.
+var DefaultPalette = [4]color.RGBA{
+	{0x00, 0x00, 0x00, 0xFF},
+	{0xFF, 0xFF, 0xFF, 0xFF},
+	{0xAA, 0xAA, 0xAA, 0xFF},
+	{0x55, 0x55, 0x55, 0xFF},
+}
+
+func (c *Chip8) colorPalette() color.Palette {
+	p := make(color.Palette, len(c.Palette))
+	for i, col := range c.Palette {
+		p[i] = col
+	}
+	return p
+}
+
+// frameImage renders the display with each pixel as a scale x scale block.
+func (c *Chip8) frameImage(scale int) (*image.Paletted, error) {
+	if scale < 1 {
+		return nil, fmt.Errorf("invalid scale: %d", scale)
+	}
+	s := c.screen()
+	img := image.NewPaletted(image.Rect(0, 0, s.width()*scale, s.height()*scale), c.colorPalette())
+	for x := 0; x < s.width(); x++ {
+		for y := 0; y < s.height(); y++ {
+			index := s.planes(x, y)
+			if index == 0 {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex(x*scale+dx, y*scale+dy, index)
+				}
+			}
+		}
+	}
+	return img, nil
+}
+
+// DumpPNG writes the current frame as a PNG scaled by scale.
+func (c *Chip8) DumpPNG(w io.Writer, scale int) error {
+	img, err := c.frameImage(scale)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// RequestScreenshot asks for a PNG of the frame being run, at 1:1 scale, and
+// may be called from any goroutine. RunFrame delivers it on the returned
+// channel when the frame ends, so the image never catches a half-drawn
+// frame.
+func (c *Chip8) RequestScreenshot() <-chan []byte {
+	ch := make(chan []byte, 1)
+	c.shotMu.Lock()
+	c.shots = append(c.shots, ch)
+	c.shotMu.Unlock()
+	return ch
+}
+
+func (c *Chip8) deliverScreenshots() {
+	c.shotMu.Lock()
+	shots := c.shots
+	c.shots = nil
+	c.shotMu.Unlock()
+	if len(shots) == 0 {
+		return
+	}
+	var buf bytes.Buffer
+	c.DumpPNG(&buf, 1)
+	for _, ch := range shots {
+		ch <- buf.Bytes()
+		close(ch)
+	}
+}
+
+// GIFRecorder collects frames into an animated GIF.
+type GIFRecorder struct {
+	Scale int // pixels per CHIP-8 pixel, at least 1
+	Delay int // per frame, in 1/100s
+	anim  gif.GIF
+}
+
+func (r *GIFRecorder) AddFrame(c *Chip8) error {
+	img, err := c.frameImage(r.Scale)
+	if err != nil {
+		return err
+	}
+	r.anim.Image = append(r.anim.Image, img)
+	r.anim.Delay = append(r.anim.Delay, r.Delay)
+	return nil
+}
+
+func (r *GIFRecorder) Encode(w io.Writer) error {
+	return gif.EncodeAll(w, &r.anim)
+}
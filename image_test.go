@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+)
+
+func TestDumpPNGScale(t *testing.T) {
+	c := newTestChip(t)
+	drawAt(t, c, 1, 0, 0x80)
+	var buf bytes.Buffer
+	if err := c.DumpPNG(&buf, 8); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 512 || b.Dy() != 256 {
+		t.Fatalf("PNG is %dx%d, want 512x256", b.Dx(), b.Dy())
+	}
+	on, off := c.Palette[1], c.Palette[0]
+	for _, tc := range []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{8, 0, on}, {15, 7, on}, {7, 0, off}, {16, 0, off}, {8, 8, off},
+	} {
+		if got := color.RGBAModel.Convert(img.At(tc.x, tc.y)); got != tc.want {
+			t.Errorf("pixel (%d,%d) = %v, want %v", tc.x, tc.y, got, tc.want)
+		}
+	}
+}
+
+func TestDumpPNGRejectsScale(t *testing.T) {
+	c := newTestChip(t)
+	if err := c.DumpPNG(&bytes.Buffer{}, 0); err == nil {
+		t.Error("DumpPNG accepted scale 0")
+	}
+}
+
+func TestGIFRecorderScale(t *testing.T) {
+	c := newTestChip(t)
+	r := GIFRecorder{Scale: 4, Delay: 2}
+	for i := 0; i < 2; i++ {
+		if err := r.AddFrame(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := r.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 2 || anim.Config.Width != 256 || anim.Config.Height != 128 {
+		t.Errorf("GIF has %d frames of %dx%d, want 2 of 256x128", len(anim.Image), anim.Config.Width, anim.Config.Height)
+	}
+}
+
+func TestGIFRecorderRejectsBadScale(t *testing.T) {
+	c := newTestChip(t)
+	for _, scale := range []int{0, -1} {
+		r := GIFRecorder{Scale: scale}
+		if err := r.AddFrame(c); err == nil {
+			t.Errorf("AddFrame accepted Scale %d", scale)
+		}
+		if len(r.anim.Image) != 0 {
+			t.Errorf("Scale %d: frame recorded despite the error", scale)
+		}
+	}
+}
+
+func TestPalettePerPlaneBits(t *testing.T) {
+	c := newTestChip(t)
+	c.Palette = [4]color.RGBA{
+		{1, 0, 0, 0xFF}, {2, 0, 0, 0xFF}, {3, 0, 0, 0xFF}, {4, 0, 0, 0xFF},
+	}
+	for bits := byte(0); bits < 4; bits++ {
+		c.display[bits][0] = bits
+	}
+	img, err := c.frameImage(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for bits := 0; bits < 4; bits++ {
+		if got := c.ColorAt(bits, 0); got != c.Palette[bits] {
+			t.Errorf("ColorAt for planes %02b = %v, want %v", bits, got, c.Palette[bits])
+		}
+		if got := color.RGBAModel.Convert(img.At(bits, 0)); got != c.Palette[bits] {
+			t.Errorf("image pixel for planes %02b = %v, want %v", bits, got, c.Palette[bits])
+		}
+	}
+}
+
+func TestRequestScreenshot(t *testing.T) {
+	c := newTestChip(t, 0xA300, 0xD011, 0x1204)
+	c.memory[0x300] = 0x80
+	shot := c.RequestScreenshot()
+	select {
+	case <-shot:
+		t.Fatal("screenshot delivered before the frame ended")
+	default:
+	}
+	if err := c.RunFrame(); err != nil {
+		t.Fatal(err)
+	}
+	data, ok := <-shot
+	if !ok {
+		t.Fatal("screenshot channel closed without an image")
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 64 || b.Dy() != 32 {
+		t.Errorf("screenshot is %dx%d, want 64x32", b.Dx(), b.Dy())
+	}
+	if got := color.RGBAModel.Convert(img.At(0, 0)); got != c.Palette[1] {
+		t.Errorf("screenshot pixel (0,0) = %v, want the drawn colour", got)
+	}
+}
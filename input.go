@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type keyEvent struct {
+	key     byte
+	pressed bool
+}
+
+// FeedKeys queues a script of key events like "5+ 5- A+ A-" (press 5,
+// release 5, press A, release A). One event is applied per Cycle.
+func (c *Chip8) FeedKeys(script string) error {
+	var events []keyEvent
+	for _, tok := range strings.Fields(script) {
+		if len(tok) != 2 || (tok[1] != '+' && tok[1] != '-') {
+			return fmt.Errorf("bad key event: %q", tok)
+		}
+		key, ok := hexKey(tok[0])
+		if !ok {
+			return fmt.Errorf("bad key in event: %q", tok)
+		}
+		events = append(events, keyEvent{key: key, pressed: tok[1] == '+'})
+	}
+	c.keyScript = append(c.keyScript, events...)
+	return nil
+}
+
+func (c *Chip8) applyKeyEvent() {
+	if len(c.keyScript) == 0 {
+		return
+	}
+	ev := c.keyScript[0]
+	c.keyScript = c.keyScript[1:]
+	c.SetKey(ev.key, ev.pressed)
+}
+
+// QueueKey records a key event from any goroutine, e.g. a host input
+// handler. Queued events are applied together at the start of the next
+// RunFrame, never mid-frame, so EX9E and FX0A see the same key state for a
+// given event timeline however the host's input thread is scheduled.
+func (c *Chip8) QueueKey(key byte, pressed bool) {
+	c.keyMu.Lock()
+	defer c.keyMu.Unlock()
+	c.keyQueue = append(c.keyQueue, keyEvent{key: key & 0x0F, pressed: pressed})
+}
+
+func (c *Chip8) applyQueuedKeys() {
+	c.keyMu.Lock()
+	queued := c.keyQueue
+	c.keyQueue = nil
+	c.keyMu.Unlock()
+	for _, ev := range queued {
+		c.SetKey(ev.key, ev.pressed)
+	}
+}
+
+// SetKey presses or releases a key. With StickyKeys a release is held back
+// until the press has been read, so a tap between two polls is not lost.
+// Call it from the goroutine that runs Cycle; other goroutines use QueueKey.
+func (c *Chip8) SetKey(key byte, pressed bool) {
+	key &= 0x0F
+	if !pressed && c.StickyKeys && c.keyUnread[key] {
+		c.keyReleasing[key] = true
+		return
+	}
+	wasDown := c.keys[key]
+	c.keys[key] = pressed
+	c.keyFrames[key] = 0
+	if pressed {
+		c.pressSeq++
+		c.keyPressed[key] = c.pressSeq
+	}
+	c.keyUnread[key] = pressed
+	c.keyReleasing[key] = false
+	// a release, or a press of a key that was up, makes the key fresh for
+	// FX0A again; repeated press events for a held key do not
+	if !pressed || !wasDown {
+		c.keyWaited[key] = false
+	}
+}
+
+// readKey is how instructions poll a key: it marks the press seen and
+// completes any release StickyKeys held back.
+func (c *Chip8) readKey(key byte) bool {
+	key &= 0x0F
+	pressed := c.keys[key] && !c.keySuppressed(key)
+	c.keyUnread[key] = false
+	if c.keyReleasing[key] {
+		c.SetKey(key, false)
+	}
+	return pressed
+}
+
+// tickKeys counts how long each key has been held and applies
+// AutoReleaseFrames.
+func (c *Chip8) tickKeys() {
+	for k := range c.keys {
+		if !c.keys[k] {
+			continue
+		}
+		c.keyFrames[k]++
+		if c.AutoReleaseFrames > 0 && c.keyFrames[k] >= c.AutoReleaseFrames {
+			c.SetKey(byte(k), false)
+		}
+	}
+}
+
+// KeyHeldFrames returns how many 60Hz frames key has been held, 0 when it is
+// up. Like SetKey it must be called from the goroutine that runs Cycle.
+func (c *Chip8) KeyHeldFrames(key byte) int {
+	return c.keyFrames[key&0x0F]
+}
+
+// keySuppressed reports whether key shares an ExclusiveKeyGroups group with
+// a key pressed after it, e.g. left and right held together.
+func (c *Chip8) keySuppressed(key byte) bool {
+	for _, group := range c.ExclusiveKeyGroups {
+		if !slices.Contains(group, key) {
+			continue
+		}
+		for _, other := range group {
+			other &= 0x0F
+			if other != key && c.keys[other] && c.keyPressed[other] > c.keyPressed[key] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hexKey(ch byte) (byte, bool) {
+	switch {
+	case ch >= '0' && ch <= '9':
+		return ch - '0', true
+	case ch >= 'A' && ch <= 'F':
+		return ch - 'A' + 10, true
+	case ch >= 'a' && ch <= 'f':
+		return ch - 'a' + 10, true
+	}
+	return 0, false
+}
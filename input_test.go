@@ -0,0 +1,298 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFX0AHeldKeyNeedsFreshPress(t *testing.T) {
+	for _, onRelease := range []bool{false, true} {
+		c := newTestChip(t, 0xF00A, 0xF10A, 0x1204)
+		c.Quirks.WaitKeyOnRelease = onRelease
+		c.SetKey(5, true)
+		step(t, c, 1)
+		if onRelease {
+			c.SetKey(5, false)
+			step(t, c, 1)
+			c.SetKey(5, true)
+		}
+		if c.PC != 0x202 || c.V[0] != 5 {
+			t.Fatalf("onRelease=%v: first FX0A: PC=%03X V0=%X", onRelease, c.PC, c.V[0])
+		}
+		step(t, c, 3)
+		if c.PC != 0x202 {
+			t.Fatalf("onRelease=%v: second FX0A completed on a held key, PC=%03X", onRelease, c.PC)
+		}
+		c.SetKey(5, true) // key repeat from the host
+		step(t, c, 1)
+		if c.PC != 0x202 {
+			t.Fatalf("onRelease=%v: repeated press event completed FX0A", onRelease)
+		}
+		c.SetKey(5, false)
+		c.SetKey(5, true)
+		step(t, c, 1)
+		if onRelease {
+			c.SetKey(5, false)
+			step(t, c, 1)
+		}
+		if c.PC != 0x204 || c.V[1] != 5 {
+			t.Errorf("onRelease=%v: fresh press: PC=%03X V1=%X, want 204 and 5", onRelease, c.PC, c.V[1])
+		}
+	}
+}
+
+func TestFX0APressReleaseSequence(t *testing.T) {
+	for _, onRelease := range []bool{false, true} {
+		c := newTestChip(t, 0xF30A, 0x1202)
+		c.Quirks.WaitKeyOnRelease = onRelease
+		step(t, c, 2)
+		if c.PC != 0x200 {
+			t.Fatalf("onRelease=%v: FX0A completed with no key, PC=%03X", onRelease, c.PC)
+		}
+		c.SetKey(9, true)
+		step(t, c, 1)
+		if done := c.PC == 0x202; done == onRelease {
+			t.Errorf("onRelease=%v: after press PC=%03X", onRelease, c.PC)
+		}
+		c.SetKey(9, false)
+		if onRelease {
+			step(t, c, 1)
+		}
+		if c.PC != 0x202 || c.V[3] != 9 {
+			t.Errorf("onRelease=%v: after release PC=%03X V3=%X, want 202 and 9", onRelease, c.PC, c.V[3])
+		}
+	}
+}
+
+func TestFX0AStickyTapThenHold(t *testing.T) {
+	c := newTestChip(t, 0xF00A, 0xF10A, 0x1204)
+	c.Quirks.WaitKeyOnRelease = false
+	c.StickyKeys = true
+	c.SetKey(5, true)
+	c.SetKey(5, false) // tap between polls, held back until read
+	step(t, c, 1)
+	if c.PC != 0x202 || c.V[0] != 5 {
+		t.Fatalf("tap: PC=%03X V0=%X", c.PC, c.V[0])
+	}
+	if c.keys[5] {
+		t.Fatal("held-back release not completed by the read")
+	}
+	c.SetKey(5, true)
+	step(t, c, 1)
+	if c.PC != 0x204 || c.V[1] != 5 {
+		t.Errorf("held press after tap: PC=%03X V1=%X, want 204 and 5", c.PC, c.V[1])
+	}
+}
+
+func TestFeedKeysScript(t *testing.T) {
+	c := newTestChip(t, 0x6005, 0xE09E, 0x6101, 0xE0A1, 0x6201, 0x120A)
+	if err := c.FeedKeys("5+ A+ 5- A-"); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []struct {
+		key5, keyA bool
+		pc         uint16
+	}{
+		{true, false, 0x202},  // 6005
+		{true, true, 0x206},   // EX9E sees 5 down and skips
+		{false, true, 0x20A},  // EXA1 sees 5 up and skips
+		{false, false, 0x20A}, // jump to self
+	} {
+		step(t, c, 1)
+		if c.keys[5] != want.key5 || c.keys[0xA] != want.keyA || c.PC != want.pc {
+			t.Errorf("step %d: key5=%v keyA=%v PC=%03X, want %v %v %03X",
+				i+1, c.keys[5], c.keys[0xA], c.PC, want.key5, want.keyA, want.pc)
+		}
+	}
+	if c.V[1] != 0 || c.V[2] != 0 {
+		t.Errorf("skipped instructions ran: V1=%d V2=%d", c.V[1], c.V[2])
+	}
+}
+
+func TestFeedKeysRejectsBadEvents(t *testing.T) {
+	c := newTestChip(t)
+	for _, script := range []string{"5", "5*", "G+", "55+"} {
+		if err := c.FeedKeys(script); err == nil {
+			t.Errorf("FeedKeys(%q) succeeded", script)
+		}
+	}
+}
+
+func TestAutoReleaseFrames(t *testing.T) {
+	c := newTestChip(t)
+	c.AutoReleaseFrames = 3
+	c.SetKey(7, true)
+	for frame := 1; frame < 3; frame++ {
+		c.TickFrame()
+		if !c.keys[7] {
+			t.Fatalf("key released after %d frames", frame)
+		}
+	}
+	c.TickFrame()
+	if c.keys[7] {
+		t.Error("key still held after 3 frames")
+	}
+	if c.KeyHeldFrames(7) != 0 {
+		t.Errorf("KeyHeldFrames = %d after release", c.KeyHeldFrames(7))
+	}
+}
+
+// TestAutoReleaseWithStartTimers presses keys and runs instructions while
+// the StartTimers goroutine is ticking; run it with -race.
+func TestAutoReleaseWithStartTimers(t *testing.T) {
+	c := newTestChip(t, 0xE59E, 0x1200, 0x1200)
+	c.TimerHz = 1000
+	c.AutoReleaseFrames = 2
+	c.StartTimers()
+	for i := 0; i < 200; i++ {
+		c.SetKey(byte(i), i%3 != 0)
+		if err := c.Cycle(); err != nil {
+			t.Fatal(err)
+		}
+		c.KeyHeldFrames(byte(i))
+	}
+	c.SetKey(5, true)
+	for deadline := time.Now().Add(5 * time.Second); c.keys[5]; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("held key never auto-released under StartTimers")
+		}
+		if err := c.Cycle(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAutoReleaseDisabled(t *testing.T) {
+	c := newTestChip(t)
+	c.SetKey(7, true)
+	for i := 0; i < 100; i++ {
+		c.TickFrame()
+	}
+	if !c.keys[7] {
+		t.Error("key released with AutoReleaseFrames 0")
+	}
+}
+
+func TestQueueKeyDeterministic(t *testing.T) {
+	// V0 counts the loop passes that see key 5 held.
+	program := []uint16{0xE59E, 0x1206, 0x7001, 0x1200}
+	timeline := map[int][]keyEvent{2: {{5, true}}, 5: {{5, false}}, 7: {{5, true}, {5, false}}}
+	run := func() byte {
+		c := newTestChip(t, program...)
+		c.V[5] = 5
+		for frame := 0; frame < 10; frame++ {
+			done := make(chan struct{})
+			go func() { // as a host input thread would
+				defer close(done)
+				for _, ev := range timeline[frame] {
+					c.QueueKey(ev.key, ev.pressed)
+				}
+			}()
+			<-done
+			if err := c.RunFrame(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return c.V[0]
+	}
+	first := run()
+	if first == 0 {
+		t.Fatal("queued press never seen")
+	}
+	for i := 0; i < 5; i++ {
+		if got := run(); got != first {
+			t.Fatalf("run %d counted %d passes, first run %d", i+2, got, first)
+		}
+	}
+}
+
+func TestQueueKeyAppliedAtFrameStart(t *testing.T) {
+	c := newTestChip(t, 0x1200)
+	c.QueueKey(5, true)
+	if c.keys[5] {
+		t.Fatal("queued key applied before the frame")
+	}
+	if err := c.RunFrame(); err != nil {
+		t.Fatal(err)
+	}
+	if !c.keys[5] {
+		t.Error("queued key not applied by RunFrame")
+	}
+}
+
+func TestStickyTapSeenByOnePoll(t *testing.T) {
+	for _, sticky := range []bool{false, true} {
+		c := newTestChip(t, 0x6005, 0xE09E, 0x1204, 0x6101, 0xE0A1, 0x120A, 0x6201)
+		c.StickyKeys = sticky
+		step(t, c, 1)
+		c.SetKey(5, true)
+		c.SetKey(5, false) // released before the poll
+		step(t, c, 2)
+		if seen := c.V[1] == 1; seen != sticky {
+			t.Errorf("StickyKeys=%v: EX9E saw the tap = %v", sticky, seen)
+		}
+		if sticky {
+			// the poll consumed the press, so the key now reads up
+			step(t, c, 2)
+			if c.V[2] != 1 {
+				t.Errorf("key still down after the tap was read, PC=%03X", c.PC)
+			}
+		}
+	}
+}
+
+func TestKeyHeldFrames(t *testing.T) {
+	c := newTestChip(t)
+	c.SetKey(0xB, true)
+	if n := c.KeyHeldFrames(0xB); n != 0 {
+		t.Fatalf("KeyHeldFrames = %d before any frame", n)
+	}
+	for i := 0; i < 5; i++ {
+		c.TickFrame()
+	}
+	if n := c.KeyHeldFrames(0xB); n != 5 {
+		t.Errorf("KeyHeldFrames = %d after 5 frames, want 5", n)
+	}
+	if n := c.KeyHeldFrames(0xC); n != 0 {
+		t.Errorf("KeyHeldFrames for an unpressed key = %d", n)
+	}
+	c.SetKey(0xB, false)
+	if n := c.KeyHeldFrames(0xB); n != 0 {
+		t.Errorf("KeyHeldFrames = %d after release, want 0", n)
+	}
+	c.SetKey(0xB, true)
+	c.TickFrame()
+	if n := c.KeyHeldFrames(0xB); n != 1 {
+		t.Errorf("KeyHeldFrames = %d one frame into a new press, want 1", n)
+	}
+}
+
+func TestExclusiveKeyGroups(t *testing.T) {
+	// V2 and V3 count the polls that see keys 4 and 6 held.
+	c := newTestChip(t, 0x6004, 0x6106, 0xE0A1, 0x7201, 0xE1A1, 0x7301)
+	c.ExclusiveKeyGroups = [][]byte{{4, 6}}
+	c.SetKey(4, true)
+	c.SetKey(6, true)
+	step(t, c, 5)
+	if c.V[2] != 0 || c.V[3] != 1 {
+		t.Errorf("with 4 then 6 held: key 4 seen %d, key 6 seen %d, want 0 and 1", c.V[2], c.V[3])
+	}
+	c.SetKey(6, false)
+	c.PC = 0x204
+	step(t, c, 3)
+	if c.V[2] != 1 {
+		t.Errorf("key 4 not seen after 6 was released")
+	}
+}
+
+func TestFX0AWithExclusiveGroup(t *testing.T) {
+	c := newTestChip(t, 0xF50A)
+	c.Quirks.WaitKeyOnRelease = false
+	c.ExclusiveKeyGroups = [][]byte{{4, 6}}
+	c.SetKey(6, true)
+	c.SetKey(4, true)
+	step(t, c, 1)
+	if c.PC != 0x202 || c.V[5] != 4 {
+		t.Errorf("FX0A stored %X at PC %03X, want the later key 4", c.V[5], c.PC)
+	}
+}
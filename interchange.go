@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// interchangeState is the JSON machine state written by common CHIP-8 test
+// tools. Keys match case-insensitively and the alternative names some tools
+// use are accepted:
+//
+//	{
+//	  "pc": 512, "sp": 0,
+//	  "i": 554,                     // or "index"
+//	  "v": [0, 1, ...],             // or "registers", 16 values
+//	  "delay_timer": 0,             // or "dt", "delay"
+//	  "sound_timer": 0,             // or "st", "sound"
+//	  "stack": [514],               // return addresses, oldest first
+//	  "memory": [0, 0, ...]         // or "ram", bytes from address 0
+//	}
+type interchangeState struct {
+	PC         uint16   `json:"pc"`
+	I          uint16   `json:"i"`
+	Index      *uint16  `json:"index"`
+	SP         *byte    `json:"sp"`
+	V          []int    `json:"v"`
+	Registers  []int    `json:"registers"`
+	DelayTimer byte     `json:"delay_timer"`
+	DT         *byte    `json:"dt"`
+	Delay      *byte    `json:"delay"`
+	SoundTimer byte     `json:"sound_timer"`
+	ST         *byte    `json:"st"`
+	Sound      *byte    `json:"sound"`
+	Stack      []uint16 `json:"stack"`
+	Memory     []int    `json:"memory"`
+	RAM        []int    `json:"ram"`
+}
+
+// ImportState loads registers, timers, stack and memory from the JSON
+// interchange format described on interchangeState, so a known-good state
+// from another emulator can be continued here. Fields that are absent keep
+// their zero value; memory is only written where given.
+func (c *Chip8) ImportState(r io.Reader) error {
+	var in interchangeState
+	if err := json.NewDecoder(r).Decode(&in); err != nil {
+		return err
+	}
+	s := State{PC: in.PC, I: in.I, DT: in.DelayTimer, ST: in.SoundTimer, Stack: in.Stack}
+	if in.Index != nil {
+		s.I = *in.Index
+	}
+	for _, p := range []*byte{in.DT, in.Delay} {
+		if p != nil {
+			s.DT = *p
+		}
+	}
+	for _, p := range []*byte{in.ST, in.Sound} {
+		if p != nil {
+			s.ST = *p
+		}
+	}
+	s.SP = byte(len(in.Stack))
+	if in.SP != nil {
+		s.SP = *in.SP
+	}
+
+	v := in.V
+	if v == nil {
+		v = in.Registers
+	}
+	if len(v) > len(s.V) {
+		return fmt.Errorf("%d V registers, want at most %d", len(v), len(s.V))
+	}
+	for i, val := range v {
+		if val < 0 || val > 0xFF {
+			return fmt.Errorf("V%X out of range: %d", i, val)
+		}
+		s.V[i] = byte(val)
+	}
+
+	mem := in.Memory
+	if mem == nil {
+		mem = in.RAM
+	}
+	if len(mem) > len(c.memory) {
+		return fmt.Errorf("%d bytes of memory, want at most %d", len(mem), len(c.memory))
+	}
+	s.Memory = make(map[uint16]byte, len(mem))
+	for addr, val := range mem {
+		if val < 0 || val > 0xFF {
+			return fmt.Errorf("memory byte at %04X out of range: %d", addr, val)
+		}
+		s.Memory[uint16(addr)] = byte(val)
+	}
+	return c.SetState(s)
+}
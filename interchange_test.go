@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImportState(t *testing.T) {
+	c := newTestChip(t)
+	in := `{
+		"PC": 520, "index": 768, "registers": [1, 2, 255],
+		"dt": 30, "sound_timer": 4, "stack": [514, 530],
+		"ram": [240, 144, 144, 144, 240]
+	}`
+	if err := c.ImportState(strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	s := c.State()
+	if s.PC != 520 || s.I != 768 || s.SP != 2 || s.DT != 30 || s.ST != 4 {
+		t.Errorf("State = %+v", s)
+	}
+	if s.V[0] != 1 || s.V[1] != 2 || s.V[2] != 255 || s.V[3] != 0 {
+		t.Errorf("V = % X", s.V)
+	}
+	if s.Stack[0] != 514 || s.Stack[1] != 530 {
+		t.Errorf("stack = %v", s.Stack[:2])
+	}
+	if got := c.memory[:5]; string(got) != "\xF0\x90\x90\x90\xF0" {
+		t.Errorf("memory = % X", got)
+	}
+}
+
+func TestImportStateRejectsBadValues(t *testing.T) {
+	c := newTestChip(t)
+	for _, in := range []string{
+		`{"pc": 512, "v": [256]}`,
+		`{"pc": 512, "v": [0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0]}`,
+		`{"pc": 512, "memory": [-1]}`,
+		`{"pc": 4095}`,
+		`not json`,
+	} {
+		if err := c.ImportState(strings.NewReader(in)); err == nil {
+			t.Errorf("ImportState(%s) succeeded", in)
+		}
+	}
+}
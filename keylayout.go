@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// keyGrid is the COSMAC VIP keypad as laid over the left of a keyboard, row
+// by row: the four keys under 1 2 3 4 on QWERTY are 1 2 3 C, and so on.
+var keyGrid = [4][4]byte{
+	{0x1, 0x2, 0x3, 0xC},
+	{0x4, 0x5, 0x6, 0xD},
+	{0x7, 0x8, 0x9, 0xE},
+	{0xA, 0x0, 0xB, 0xF},
+}
+
+// keyLayouts gives, for each supported keyboard layout, the characters its
+// keys at the keyGrid positions type, unshifted, so each layout puts the
+// keypad under the same fingers. AZERTY's top row also accepts the digits
+// it types with shift.
+var keyLayouts = map[string][4]string{
+	"qwerty": {"1234", "qwer", "asdf", "zxcv"},
+	"qwertz": {"1234", "qwer", "asdf", "yxcv"},
+	"azerty": {"&é\"'", "azer", "qsdf", "wxcv"},
+	"dvorak": {"1234", "',.p", "aoeu", ";qjk"},
+}
+
+// KeyLayouts returns the names accepted by SetKeyLayout.
+func KeyLayouts() []string {
+	var names []string
+	for name := range keyLayouts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetKeyLayout selects the keyboard layout KeyForChar translates from, by a
+// name from KeyLayouts, ignoring case. The default is QWERTY.
+func (c *Chip8) SetKeyLayout(name string) error {
+	name = strings.ToLower(name)
+	rows, ok := keyLayouts[name]
+	if !ok {
+		return fmt.Errorf("unknown key layout %q, want one of %s", name, strings.Join(KeyLayouts(), ", "))
+	}
+	layout := map[rune]byte{}
+	for r, row := range rows {
+		for col, ch := range []rune(row) {
+			layout[ch] = keyGrid[r][col]
+		}
+	}
+	if name == "azerty" {
+		for col, ch := range "1234" {
+			layout[ch] = keyGrid[0][col]
+		}
+	}
+	c.keyLayout = layout
+	return nil
+}
+
+// KeyForChar returns the key a host keyboard character stands for under the
+// selected layout, so a front end can pass it to SetKey or QueueKey.
+func (c *Chip8) KeyForChar(ch rune) (byte, bool) {
+	if c.keyLayout == nil {
+		c.SetKeyLayout("qwerty")
+	}
+	key, ok := c.keyLayout[unicode.ToLower(ch)]
+	return key, ok
+}
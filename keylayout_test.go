@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+func TestKeyLayoutsSamePositions(t *testing.T) {
+	// pairs of characters typed by the same physical key on QWERTY and AZERTY
+	for _, tc := range []struct {
+		qwerty, azerty rune
+		key            byte
+	}{
+		{'1', '&', 0x1},
+		{'q', 'a', 0x4},
+		{'w', 'z', 0x5},
+		{'a', 'q', 0x7},
+		{'z', 'w', 0xA},
+		{'v', 'v', 0xF},
+	} {
+		q, a := newTestChip(t), newTestChip(t)
+		if err := a.SetKeyLayout("AZERTY"); err != nil {
+			t.Fatal(err)
+		}
+		if k, ok := q.KeyForChar(tc.qwerty); !ok || k != tc.key {
+			t.Errorf("qwerty %q = %X, %v, want %X", tc.qwerty, k, ok, tc.key)
+		}
+		if k, ok := a.KeyForChar(tc.azerty); !ok || k != tc.key {
+			t.Errorf("azerty %q = %X, %v, want %X", tc.azerty, k, ok, tc.key)
+		}
+	}
+}
+
+func TestKeyLayoutAzertyShiftedDigits(t *testing.T) {
+	c := newTestChip(t)
+	if err := c.SetKeyLayout("azerty"); err != nil {
+		t.Fatal(err)
+	}
+	if k, ok := c.KeyForChar('4'); !ok || k != 0xC {
+		t.Errorf("azerty '4' = %X, %v, want C", k, ok)
+	}
+	if k, ok := c.KeyForChar('Q'); !ok || k != 0x7 {
+		t.Errorf("azerty 'Q' = %X, %v, want 7", k, ok)
+	}
+}
+
+func TestSetKeyLayoutUnknown(t *testing.T) {
+	c := newTestChip(t)
+	if err := c.SetKeyLayout("colemak"); err == nil {
+		t.Error("SetKeyLayout(colemak) succeeded")
+	}
+	if _, ok := c.KeyForChar('m'); ok {
+		t.Error("'m' maps to a key under the default layout")
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"log"
+	"os"
+)
+
+// Logger receives diagnostics such as skipped unknown opcodes. *log.Logger
+// satisfies it.
+type Logger interface {
+	Printf(format string, args ...any)
+}
+
+var defaultLogger Logger = log.New(os.Stderr, "", 0)
+
+type nopLogger struct{}
+
+func (nopLogger) Printf(string, ...any) {}
+
+// NopLogger discards everything, for quiet operation.
+var NopLogger Logger = nopLogger{}
+
+func (c *Chip8) logf(format string, args ...any) {
+	if c.Logger == nil {
+		defaultLogger.Printf(format, args...)
+		return
+	}
+	c.Logger.Printf(format, args...)
+}
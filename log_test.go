@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// captureLogger records every formatted message.
+type captureLogger struct{ lines []string }
+
+func (l *captureLogger) Printf(format string, args ...any) {
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func TestLoggerUnknownOpcode(t *testing.T) {
+	c := newTestChip(t, 0x6001, 0xE1FF)
+	log := &captureLogger{}
+	c.Logger = log
+	step(t, c, 2)
+	if len(log.lines) != 1 || !strings.Contains(log.lines[0], "E1FF") || !strings.Contains(log.lines[0], "202") {
+		t.Errorf("logged %q, want one entry with E1FF at 202", log.lines)
+	}
+}
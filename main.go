@@ -1,23 +1,154 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
+	"flag"
 	"fmt"
+	"image"
+	"image/color"
+	"io"
 	"math/rand"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type Chip8 struct {
 	memory  [4096]byte
-	display [64][32]bool // 64 x 32 display
-	PC      uint16       // program counter
-	I       uint16       // index register
-	stack   [16]uint16   // stack for subroutines
-	SP      byte         // stack pointer
-	V       [16]byte     // 8 bit general registers
-	DT      byte         // delay timer
-	ST      byte         // sound timer
+	display [128][64]byte // plane bits per pixel, 64 x 32 unless hires
+	PC      uint16        // program counter
+	I       uint16        // index register
+	stack   []uint16      // stack for subroutines
+	SP      byte          // stack pointer
+	V       [16]byte      // 8 bit general registers
+	DT      byte          // delay timer
+	ST      byte          // sound timer
 	keys    [16]bool
+	beeping bool
+	beepFor int        // frames the beep must still sound, see MinBeepFrames
+	timerMu sync.Mutex // guards DT, ST, beeping and beepFor
+	mu      sync.Mutex // serializes Step with State, SetState, ReadRange and Undo
+	Cycles  uint64     // instructions executed
+	opPC    uint16     // address of the last fetched instruction
+	Faulted bool       // last instruction failed, see RetryLast
+	faultPC uint16
+	pitch   byte // XO-CHIP audio pitch, 64 = 440Hz
+	hires   bool // SCHIP/XO-CHIP 128 x 64 mode
+	planes  byte // XO-CHIP plane mask used by draw and clear
+
+	waitKey        byte // key FX0A saw pressed while waiting for its release
+	waitingRelease bool
+
+	Quirks           Quirks // zero before the first Init means DefaultQuirks; see SetQuirks
+	quirksSet        bool   // Quirks was chosen by SetQuirks or settled by Init
+	Variant          Variant
+	Strict           bool      // unknown opcodes return ErrUnknownOpcode instead of being skipped
+	DisabledOpsFault bool      // opcodes switched off by DisableOpcode fault instead of being skipped
+	StackSize        int       // stack levels, default 16, max 255
+	FontAddress      uint16    // where Init places the fontsets, e.g. 0x050; both must fit below 0x200
+	MemFillByte      byte      // Init fills memory with this before the fontset and ROM, to expose stray reads
+	OddROM           bool      // loaded ROM has an odd byte length
+	TrimPadding      bool      // DisassembleProgram stops at CodeLength, leaving out ROM padding
+	RandSource       io.Reader // scripted bytes for CXNN, PRNG when nil
+	Logger           Logger    // diagnostics, stderr when nil; NopLogger silences them
+	DebugEvery       int       // call OnDebugTick every this many cycles, 0 = never
+
+	// OnFlagWrite, when set, is called whenever an instruction writes VF as
+	// a flag, with the instruction address and mnemonic.
+	OnFlagWrite func(pc uint16, old, new byte, op string)
+
+	// OnBeep and OnBeepEnd, when set, are called as the beeper starts and
+	// stops, e.g. to flash a border when audio is unavailable. They run with
+	// the timer lock held and must not call the timer accessors.
+	OnBeep    func()
+	OnBeepEnd func()
+
+	// OnDebugTick, when set, is called after every DebugEvery-th cycle.
+	OnDebugTick func(*Chip8)
+
+	AutoReleaseFrames int  // release pressed keys after this many frames, 0 = never
+	StickyKeys        bool // hold a released key until EX9E, EXA1 or FX0A has seen it pressed
+	keyUnread         [16]bool
+	keyWaited         [16]bool // pressed keys that already satisfied an FX0A
+	keyReleasing      [16]bool
+	MinBeepFrames     int // shortest audible beep for a nonzero ST, in frames
+	keyFrames         [16]int
+	keyQueue          []keyEvent
+	keyMu             sync.Mutex // guards keyQueue
+
+	// ExclusiveKeyGroups lists keys that cannot be held together, such as
+	// left and right; when several in a group are down, instructions only
+	// see the one pressed last.
+	ExclusiveKeyGroups [][]byte
+	keyPressed         [16]uint64 // pressSeq at each key's latest press
+	pressSeq           uint64
+	keyLayout          map[rune]byte // host characters to keys, see SetKeyLayout
+
+	DetectIdle bool // track SuspectedIdle
+	IdleWindow int  // instructions of history for DetectIdle, default 64
+	idle       bool
+	idleRing   []uint64
+	idleSeen   map[uint64]int
+
+	CrashFrames int // frames of display history kept for CrashDump, 0 = off
+	crashRing   []crashFrame
+
+	UndoDepth int // instructions run by Step that Undo can revert, 0 = off
+	undoLog   []undoEntry
+
+	CompatWarnings bool // collect quirk suggestions for Warnings
+	warnings       []string
+	warned         map[string]bool
+
+	InstructionsPerFrame int  // default 8, about 500Hz
+	MaxDrawsPerFrame     int  // end a frame after this many DXYN, 0 = unlimited
+	AccurateTiming       bool // schedule by VIP machine cycles instead of instruction count
+	ClockHz              int  // machine cycles per second, default 220080
+	TimerHz              int  // TickFrame rate for StartTimers and SyncTimers, default 60
+	drawsThisFrame       int
+	cycleBudget          int
+
+	drawStats   DrawStats
+	Palette     [4]color.RGBA // render colours indexed by plane bits
+	Ghosting    bool          // renderers OR the previous frame into the current one
+	FlipX       bool          // render mirrored horizontally
+	FlipY       bool          // render mirrored vertically
+	Rotate180   bool          // render upside down
+	Invert      bool          // render unlit pixels lit and lit pixels unlit
+	DrawMode    DrawMode      // DXYN combines sprites by XOR, or OR for debugging
+	prevDisplay [128][64]byte
+	shots       []chan []byte
+	shotMu      sync.Mutex // guards shots
+	dirty       bool
+	dirtyMin    image.Point
+	dirtyMax    image.Point // inclusive
+
+	PhosphorDecay time.Duration // afterglow time constant for Intensity, 0 = off
+	phosphor      [128][64]float64
+
+	DoubleBuffer bool // renderers show the frame last passed to Present
+	front        atomic.Pointer[presentedFrame]
+
+	FrameAudio bool // RunFrame generates beeper samples for AudioStream
+	audio      frameAudio
+
+	romLen      int
+	rom         []byte
+	romPaths    []string
+	keyScript   []keyEvent
+	opCounts    map[string]uint64
+	breakpoints map[uint16]bool
+	disabledOps map[string]bool
+	frames      atomic.Uint64
+	keyTicks    atomic.Uint64 // frames StartTimers counted that Cycle has not aged keys for
+	clock       func() time.Time
+	timerAt     time.Time
+	rng         *rand.Rand
+	trace       io.Writer
+	regLog      io.Writer
+	stats       statsSample
 }
 
 var fontset = [80]byte{
@@ -39,83 +170,340 @@ var fontset = [80]byte{
 	0xF0, 0x80, 0xF0, 0x80, 0x80, // F
 }
 
+// bigFontset is the SCHIP 8x10 font for FX30, which only has digits 0-9. It
+// follows the small font in memory.
+var bigFontset = [100]byte{
+	0x3C, 0x7E, 0xE7, 0xC3, 0xC3, 0xC3, 0xC3, 0xE7, 0x7E, 0x3C, // 0
+	0x18, 0x38, 0x58, 0x18, 0x18, 0x18, 0x18, 0x18, 0x18, 0x3C, // 1
+	0x3E, 0x7F, 0xC3, 0x06, 0x0C, 0x18, 0x30, 0x60, 0xFF, 0xFF, // 2
+	0x3C, 0x7E, 0xC3, 0x03, 0x0E, 0x0E, 0x03, 0xC3, 0x7E, 0x3C, // 3
+	0x06, 0x0E, 0x1E, 0x36, 0x66, 0xC6, 0xFF, 0xFF, 0x06, 0x06, // 4
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFC, 0xFE, 0x03, 0xC3, 0x7E, 0x3C, // 5
+	0x3E, 0x7C, 0xC0, 0xC0, 0xFC, 0xFE, 0xC3, 0xC3, 0x7E, 0x3C, // 6
+	0xFF, 0xFF, 0x03, 0x06, 0x0C, 0x18, 0x30, 0x60, 0x60, 0x60, // 7
+	0x3C, 0x7E, 0xC3, 0xC3, 0x7E, 0x7E, 0xC3, 0xC3, 0x7E, 0x3C, // 8
+	0x3C, 0x7E, 0xC3, 0xC3, 0x7F, 0x3F, 0x03, 0x03, 0x3E, 0x7C, // 9
+}
+
+func (c *Chip8) bigFontAddress() uint16 {
+	return c.FontAddress + uint16(len(fontset))
+}
+
+// Init applies configuration defaults, zeroes the timers and writes the
+// fontsets. Call it before loading a ROM; with MemFillByte set it overwrites
+// all of memory.
 func (c *Chip8) Init() {
 	c.PC = 0x200
-	copy(c.memory[0:80], fontset[:])
+	if !c.quirksSet {
+		if c.Quirks == (Quirks{}) {
+			c.Quirks = DefaultQuirks
+		}
+		c.quirksSet = true
+	}
+	if c.StackSize <= 0 {
+		c.StackSize = 16
+	}
+	if c.StackSize > 255 {
+		c.StackSize = 255
+	}
+	if c.InstructionsPerFrame <= 0 {
+		c.InstructionsPerFrame = 8
+	}
+	if c.TimerHz <= 0 {
+		c.TimerHz = 60
+	}
+	c.stack = make([]uint16, c.StackSize)
+	if c.Palette == ([4]color.RGBA{}) {
+		c.Palette = DefaultPalette
+	}
+	c.pitch = 64
+	c.planes = 1
+	c.SetDelayTimer(0)
+	c.SetSoundTimer(0)
+	SetBeepFrequency(baseBeepFrequency)
+	c.SP = 0
+	if c.MemFillByte != 0 {
+		for i := range c.memory {
+			c.memory[i] = c.MemFillByte
+		}
+	}
+	if int(c.FontAddress)+len(fontset)+len(bigFontset) > 0x200 {
+		c.logf("Font address %03X leaves no room for the fonts below 0x200, using 000", c.FontAddress)
+		c.FontAddress = 0
+	}
+	copy(c.memory[c.FontAddress:], fontset[:])
+	copy(c.memory[c.bigFontAddress():], bigFontset[:])
+}
+
+// Reset clears all machine state and reloads the current ROM; configuration
+// such as Quirks and breakpoints is kept.
+func (c *Chip8) Reset() {
+	c.memory = [4096]byte{}
+	c.display = [128][64]byte{}
+	c.markAllDirty()
+	c.prevDisplay = [128][64]byte{}
+	c.phosphor = [128][64]float64{}
+	c.front.Store(nil)
+	c.audio.reset()
+	c.drawStats = DrawStats{}
+	c.hires = false
+	c.V = [16]byte{}
+	c.I = 0
+	c.keys = [16]bool{}
+	c.keyFrames = [16]int{}
+	c.keyUnread = [16]bool{}
+	c.keyWaited = [16]bool{}
+	c.keyReleasing = [16]bool{}
+	c.keyScript = nil
+	c.keyMu.Lock()
+	c.keyQueue = nil
+	c.keyMu.Unlock()
+	c.waitingRelease = false
+	c.idle = false
+	c.idleRing = nil
+	c.idleSeen = nil
+	c.crashRing = nil
+	c.undoLog = nil
+	c.warnings = nil
+	c.warned = nil
+	c.Faulted = false
+	c.faultPC = 0
+	c.Cycles = 0
+	c.opCounts = nil
+	c.frames.Store(0)
+	c.keyTicks.Store(0)
+	c.stats = statsSample{}
+	c.Init()
+	if c.rom != nil {
+		c.loadROMData(c.rom)
+	}
+}
+
+// SoftReset restarts execution at 0x200 with cleared registers, stack and
+// timers, but unlike Reset it keeps the display and memory as they are. An
+// FX0A wait in progress and the fault RetryLast would retry are dropped too.
+func (c *Chip8) SoftReset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.PC = 0x200
+	c.V = [16]byte{}
+	c.I = 0
+	c.SetDelayTimer(0)
+	c.SetSoundTimer(0)
+	c.SP = 0
+	for i := range c.stack {
+		c.stack[i] = 0
+	}
+	c.waitingRelease = false
+	c.waitKey = 0
+	c.keyWaited = [16]bool{}
+	c.Faulted = false
+	c.faultPC = 0
 }
 
 func (c *Chip8) LoadROM(file string) error {
-	data, err := os.ReadFile(file)
+	return c.LoadROMs(file)
+}
+
+// LoadROMs concatenates the files in order and loads them contiguously at
+// 0x200.
+func (c *Chip8) LoadROMs(paths ...string) error {
+	data, err := readROMs(paths)
+	if err != nil {
+		return err
+	}
+	if err := c.loadROMData(data); err != nil {
+		return err
+	}
+	c.romPaths = paths
+	return nil
+}
+
+// ReloadROM re-reads the ROM files from disk and resets the machine to run
+// them, for an edit-assemble-reload loop.
+func (c *Chip8) ReloadROM() error {
+	if len(c.romPaths) == 0 {
+		return fmt.Errorf("no ROM file to reload")
+	}
+	data, err := readROMs(c.romPaths)
 	if err != nil {
 		return err
 	}
+	c.rom = data
+	c.Reset()
+	return nil
+}
+
+func readROMs(paths []string) ([]byte, error) {
+	var data []byte
+	for _, path := range paths {
+		part, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if part, err = gunzipROM(part); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		data = append(data, part...)
+		if len(data) > 4096-0x200 {
+			if len(paths) == 1 {
+				return nil, fmt.Errorf("ROM too large: %d bytes", len(data))
+			}
+			return nil, fmt.Errorf("ROM too large: %d bytes after %s", len(data), path)
+		}
+	}
+	return data, nil
+}
+
+// gunzipROM decompresses a gzip-compressed ROM, recognised by its magic
+// bytes whatever the file is called, and returns other data unchanged. It
+// stops just past the largest ROM that fits so the size check still fails.
+func gunzipROM(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		return data, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(io.LimitReader(zr, 4096-0x200+1))
+}
 
+func (c *Chip8) loadROMData(data []byte) error {
 	if len(data) > 4096-0x200 {
 		return fmt.Errorf("ROM too large: %d bytes", len(data))
 	}
 
 	copy(c.memory[0x200:0x200+len(data)], data)
+	c.rom = append([]byte(nil), data...)
+	c.romLen = len(data)
+	c.OddROM = len(data)%2 == 1
 	return nil
 }
 
+// StartTimers ticks the delay and sound timers TimerHz times a second on a
+// goroutine. Key state belongs to the goroutine running Cycle, so the timer
+// goroutine only counts frames and the next Cycle ages held keys for them.
 func (c *Chip8) StartTimers() {
 	go func() {
-		ticker := time.NewTicker(time.Second / 60)
+		ticker := time.NewTicker(c.timerPeriod())
 		defer ticker.Stop()
-		wasPlaying := false
 
 		for range ticker.C {
-			if c.DT > 0 {
-				c.DT--
-			}
-			if c.ST > 0 {
-				c.ST--
-				if !wasPlaying {
-					PlayBeep()
-					wasPlaying = true
-				}
-			} else if wasPlaying {
-				StopBeep()
-				wasPlaying = false
-			}
+			c.frames.Add(1)
+			c.tickTimers()
+			c.keyTicks.Add(1)
 		}
 	}()
 }
 
+// timerPeriod is the time between timer ticks, at 60Hz when TimerHz is not
+// positive, as when Init has not run.
+func (c *Chip8) timerPeriod() time.Duration {
+	hz := c.TimerHz
+	if hz <= 0 {
+		hz = 60
+	}
+	return time.Second / time.Duration(hz)
+}
+
+// SyncTimers calls TickFrame once for every TimerHz period elapsed on the
+// clock since the previous call, for hosts that drive time themselves instead
+// of using StartTimers.
+func (c *Chip8) SyncTimers() {
+	now := c.now()
+	if c.timerAt.IsZero() {
+		c.timerAt = now
+		return
+	}
+	period := c.timerPeriod()
+	for now.Sub(c.timerAt) >= period {
+		c.TickFrame()
+		c.timerAt = c.timerAt.Add(period)
+	}
+}
+
+// TickFrame advances everything that runs at 60Hz, or TimerHz: the delay
+// and sound timers, the beeper and held-key bookkeeping. It touches key
+// state, so call it from the goroutine that runs Cycle.
+func (c *Chip8) TickFrame() {
+	c.frames.Add(1)
+	c.tickTimers()
+	c.tickKeys()
+}
+
+// Fetch reads the big-endian opcode at PC (high byte first) and advances PC.
 func (c *Chip8) Fetch() (uint16, error) {
 	if int(c.PC)+1 >= len(c.memory) {
 		return 0, fmt.Errorf("PC out of bounds: %04X", c.PC)
 	}
+	if c.OddROM && int(c.PC) == 0x200+c.romLen-1 {
+		return 0, fmt.Errorf("partial instruction at end of odd-length ROM: %04X", c.PC)
+	}
 	opcode := uint16(c.memory[c.PC])<<8 | uint16(c.memory[c.PC+1])
+	c.opPC = c.PC
 	c.PC += 2
 	return opcode, nil
 }
 
-func (c *Chip8) Execute(opcode uint16) {
+// Peek returns the opcode at PC without advancing it.
+func (c *Chip8) Peek() uint16 {
+	if int(c.PC)+1 >= len(c.memory) {
+		return 0
+	}
+	return uint16(c.memory[c.PC])<<8 | uint16(c.memory[c.PC+1])
+}
+
+// skip steps over the next instruction for a taken conditional skip. On
+// XO-CHIP that may be the two-word F000 NNNN, so it steps over both words.
+func (c *Chip8) skip() {
+	if c.Variant == VariantXOChip && c.Peek() == 0xF000 {
+		c.PC += 4
+		return
+	}
+	c.PC += 2
+}
+
+func (c *Chip8) Execute(opcode uint16) error {
+	if off, err := c.opcodeDisabled(opcode); off {
+		return err
+	}
 	switch opcode & 0xF000 {
 	case 0x0000:
 		switch opcode {
-		case 0x00E0: // clear
-			for x := range c.display {
-				for y := range c.display[x] {
-					c.display[x][y] = false
-				}
+		case 0x00E0: // clear the selected planes of the active resolution
+			c.clearDisplay()
+		case 0x00FE, 0x00FF: // 00FE lores, 00FF hires (SCHIP/XO-CHIP)
+			if c.Variant == VariantChip8 {
+				return c.unknownOpcode(opcode)
 			}
+			c.hires = opcode == 0x00FF
+			c.display = [128][64]byte{}
+			c.markAllDirty()
 		case 0x00EE: // return from subroutine
 			if c.SP == 0 {
-				fmt.Println("stack underflow")
+				return c.fault("stack underflow")
 			}
 			c.SP--
 			c.PC = c.stack[c.SP]
 		default:
-			fmt.Printf("Unknown 0x0 opcode: %04X\n", opcode)
-
+			// 0NNN calls a VIP machine code routine, which no modern
+			// interpreter can run; it is skipped, or faults when Strict.
+			// Only NNN >= 100 counts, so 0000 and the 00xx SCHIP/XO-CHIP
+			// opcodes (00CN, 00FB, ...) are still reported as unknown.
+			if opcode&0x0F00 == 0 {
+				return c.unknownOpcode(opcode)
+			}
+			if c.Strict {
+				return c.fault("machine code call %04X not supported", opcode)
+			}
 		}
 	case 0x1000: // 1NNN jump
 		c.PC = opcode & 0x0FFF
 	case 0x2000: // 2NNN call subroutine
-		if int(c.SP)+1 >= len(c.stack) {
-			fmt.Println("stack overflow")
+		if int(c.SP) >= len(c.stack) {
+			return c.fault("stack overflow")
 		}
 		c.stack[c.SP] = c.PC
 		c.SP++
@@ -124,19 +512,28 @@ func (c *Chip8) Execute(opcode uint16) {
 		x := (opcode & 0x0F00) >> 8
 		nn := byte(opcode & 0x00FF)
 		if c.V[x] == nn {
-			c.PC += 2
+			c.skip()
 		}
 	case 0x4000: // 4XNN skip if vx != nn
 		x := (opcode & 0x0F00) >> 8
 		nn := byte(opcode & 0x00FF)
 		if c.V[x] != nn {
-			c.PC += 2
+			c.skip()
 		}
-	case 0x5000: // 5XY0 skip if VX != VY
+	case 0x5000:
 		x := (opcode & 0x0F00) >> 8
-		y := (opcode & 0x00FF) >> 4
-		if c.V[x] == c.V[y] {
-			c.PC += 2
+		y := (opcode & 0x00F0) >> 4
+		switch {
+		case opcode&0x000F == 0x0: // 5XY0 skip if VX == VY
+			if c.V[x] == c.V[y] {
+				c.skip()
+			}
+		case opcode&0x000F == 0x2 && c.Variant == VariantXOChip: // 5XY2 save vx..vy at I
+			return c.registerRange(x, y, true)
+		case opcode&0x000F == 0x3 && c.Variant == VariantXOChip: // 5XY3 load vx..vy from I
+			return c.registerRange(x, y, false)
+		default:
+			return c.unknownOpcode(opcode)
 		}
 	case 0x6000: // 6XNN set vx
 		x := (opcode & 0x0F00) >> 8
@@ -152,154 +549,381 @@ func (c *Chip8) Execute(opcode uint16) {
 			c.V[x] = c.V[y]
 		case 0x1: // 8XY1 vx or vy
 			c.V[x] |= c.V[y]
+			if c.Quirks.LogicResetsVF {
+				c.setFlag(0, opcode)
+			}
 		case 0x2: // 8XY2 vx and vy
 			c.V[x] &= c.V[y]
+			if c.Quirks.LogicResetsVF {
+				c.setFlag(0, opcode)
+			}
 		case 0x3: // 8XY3 vx xor vy
 			c.V[x] ^= c.V[y]
+			if c.Quirks.LogicResetsVF {
+				c.setFlag(0, opcode)
+			}
+		// flag results are computed first and written to VF last, so
+		// with X=F the flag wins over the arithmetic result
 		case 0x4: // 8XY4 vx += vy
 			sum := uint16(c.V[x]) + uint16(c.V[y])
 			c.V[x] = byte(sum & 0xFF)
-			c.V[0xF] = byte((sum >> 8) & 0x01)
-		case 0x5: // 8XY5 vx -= vy
-			diff := uint16(c.V[x]) - uint16(c.V[y])
-			c.V[x] = byte(diff & 0xFF)
-			c.V[0xF] = byte(0)
-			if c.V[x] > c.V[y] {
-				c.V[0xF] = 1
+			c.setFlag(byte((sum>>8)&0x01), opcode)
+		case 0x5: // 8XY5 vx -= vy, vf = not borrow
+			flag := byte(0)
+			if c.V[x] >= c.V[y] {
+				flag = 1
 			}
+			c.V[x] -= c.V[y]
+			c.setFlag(flag, opcode)
 		case 0x6: //8XY6 vx >>-1 vf lsb
-			c.V[0xF] = c.V[x] & 0x01
-			c.V[x] >>= 1
-		case 0x7: // 8XY7 vx = vy - vx
-			diff := uint16(c.V[y]) - uint16(c.V[x])
-			c.V[x] = byte(diff & 0xFF)
-			c.V[0xF] = byte(0) // No borrow if VY >= VX
-			if c.V[y] > c.V[x] {
-				c.V[0xF] = 1 // No borrow
+			// the flag goes in after the result, so 8FF6 and 8FFE leave the
+			// shifted-out bit in VF whichever register is the source
+			src := c.V[x]
+			if c.Quirks.ShiftUsesVY {
+				src = c.V[y]
 			}
+			c.V[x] = src >> 1
+			if c.Quirks.ShiftSetsVF {
+				c.setFlag(src&0x01, opcode)
+			}
+		case 0x7: // 8XY7 vx = vy - vx, vf = not borrow
+			flag := byte(0)
+			if c.V[y] >= c.V[x] {
+				flag = 1
+			}
+			c.V[x] = c.V[y] - c.V[x]
+			c.setFlag(flag, opcode)
 		case 0xE: // 8XYE vx <<=1 vf msb
-			c.V[0xF] = (c.V[x] & 0x80) >> 7
-			c.V[x] <<= 1
+			src := c.V[x]
+			if c.Quirks.ShiftUsesVY {
+				src = c.V[y]
+			}
+			c.V[x] = src << 1
+			if c.Quirks.ShiftSetsVF {
+				c.setFlag((src&0x80)>>7, opcode)
+			}
+		default:
+			return c.unknownOpcode(opcode)
 		}
 	case 0x9000: // 9XY0 skip if VX != VY
+		// 9XYN with N != 0 is undefined; most interpreters ignore the low
+		// nibble, so only Strict rejects it
+		if opcode&0x000F != 0 && c.Strict {
+			return c.unknownOpcode(opcode)
+		}
 		x := (opcode & 0x0F00) >> 8
 		y := (opcode & 0x00F0) >> 4
 		if c.V[x] != c.V[y] {
-			c.PC += 2
+			c.skip()
 		}
 	case 0xA000: // ANNN: Set I = NNN
 		c.I = opcode & 0x0FFF
 	case 0xB000: // BNNN: Jump to NNN + V0
-		c.PC = (opcode & 0x0FFF) + uint16(c.V[0])
+		if c.Quirks.JumpUsesVX { // BXNN: Jump to XNN + VX
+			c.PC = (opcode & 0x0FFF) + uint16(c.V[(opcode&0x0F00)>>8])
+		} else {
+			c.PC = (opcode & 0x0FFF) + uint16(c.V[0])
+		}
 	case 0xC000: // CXNN: VX = random & NN
 		x := (opcode & 0x0F00) >> 8
 		nn := byte(opcode & 0x00FF)
-		randByte := byte(rand.Intn(256))
-		c.V[x] = randByte & nn
-	case 0xD000: //dxyn
-		x := c.V[(opcode&0x0F00)>>8]
-		y := c.V[(opcode&0x00F0)>>4]
-		height := opcode & 0x000F
-		c.V[0xF] = 0
-		for row := uint16(0); row < height; row++ {
-			if c.I+row >= 4096 {
-				fmt.Printf("Sprite Draw out of bounds at I=%04X\n", c.I+row)
-				return
-			}
-			spriteByte := c.memory[c.I+row]
-			for col := uint8(0); col < 8; col++ {
-				if (spriteByte & (0x80 >> col)) != 0 {
-					xPos := (x + col) % 64
-					yPos := (y + uint8(row)) % 32
-					current := c.display[xPos][yPos]
-					c.display[xPos][yPos] = current != true
-					if current && !c.display[xPos][yPos] {
-						c.V[0xF] = 1
-					}
-				}
+		c.V[x] = c.randomByte() & nn
+	case 0xD000: // DXYN draw, VF=1 if any on-pixel was turned off
+		w, h := c.width(), c.height()
+		x := int(c.V[(opcode&0x0F00)>>8]) % w
+		y := int(c.V[(opcode&0x00F0)>>4]) % h
+		width, height := 8, int(opcode&0x000F)
+		if height == 0 && c.Variant != VariantChip8 {
+			width, height = 16, 16 // SCHIP/XO-CHIP DXY0
+		}
+		collision := false // ORed over every selected plane
+		c.drawsThisFrame++
+		c.drawStats.Sprites++
+		c.drawStats.LastX, c.drawStats.LastY, c.drawStats.LastHeight = x, y, height
+		// each selected plane takes its own sprite's worth of data from I
+		// onwards, plane 1 first, as XO-CHIP draws to both planes
+		size := width / 8 * height
+		base := int(c.I)
+		for plane := byte(1); plane <= 2; plane <<= 1 {
+			if c.planes&plane == 0 {
+				continue
+			}
+			if c.drawSprite(c.spriteSource(base), x, y, width, height, plane) {
+				collision = true
+			}
+			base += size
+		}
+		flag := byte(0)
+		if collision {
+			flag = 1
+		}
+		c.setFlag(flag, opcode)
+	case 0xE000:
+		x := (opcode & 0x0F00) >> 8
+		switch opcode & 0x00FF {
+		case 0x9E: // EX9E skip if key vx pressed
+			if c.readKey(c.V[x]) {
+				c.skip()
+			}
+		case 0xA1: // EXA1 skip if key vx not pressed
+			if !c.readKey(c.V[x]) {
+				c.skip()
 			}
+		default:
+			return c.unknownOpcode(opcode)
 		}
 	case 0xF000:
 		x := (opcode & 0x0F00) >> 8
 		switch opcode & 0x00FF {
 		case 0x07: // FX07: VX = DT
-			c.V[x] = c.DT
+			c.V[x] = c.DelayTimer()
 		case 0x15: // FX15: DT = VX
-			c.DT = c.V[x]
+			c.SetDelayTimer(c.V[x])
 		case 0x18: // FX18: ST = VX
-			c.ST = c.V[x]
+			c.SetSoundTimer(c.V[x])
 		case 0x1E: // FX1E: I += VX
-			i := uint16(c.V[x])
+			flag := byte(0)
 			if c.I+uint16(c.V[x]) > 0xFFF { // Optional: Set VF for overflow
-				c.V[0xF] = 1
-			} else {
-				c.V[0xF] = 0
+				flag = 1
+			}
+			c.I += uint16(c.V[x])
+			if c.Quirks.IWrapsAtMemEnd {
+				c.I %= uint16(len(c.memory))
+			}
+			c.setFlag(flag, opcode)
+		case 0x0A: // FX0A wait for key, stored on release or press per quirk
+			if c.waitingRelease {
+				if !c.keys[c.waitKey] {
+					c.waitingRelease = false
+					c.V[x] = c.waitKey
+					return nil
+				}
+				c.PC -= 2
+				return nil
 			}
-			c.I += i
-		case 0x0A: // FX0A wait for key press
+			// a key still held from the last FX0A has to be released and
+			// pressed again, so one press does not satisfy two waits
 			for i := 0; i < 16; i++ {
-				if c.keys[i] {
+				if c.readKey(byte(i)) && !c.keyWaited[i] {
+					c.keyWaited[i] = true
+					if c.Quirks.WaitKeyOnRelease {
+						c.waitKey = byte(i)
+						c.waitingRelease = true
+						break
+					}
 					c.V[x] = byte(i)
-					return
+					return nil
 				}
 			}
 			c.PC -= 2
-			return
+			return nil
 
-		case 0x29: // FX29 set sprite address for digit
-			c.I = uint16(c.V[x]&0x0F) * 5
+		case 0x01: // FN01 select drawing planes (XO-CHIP)
+			if c.Variant != VariantXOChip {
+				return c.unknownOpcode(opcode)
+			}
+			c.planes = byte(x) & 0x3
+		case 0x3A: // FX3A set audio pitch (XO-CHIP)
+			if c.Variant != VariantXOChip {
+				return c.unknownOpcode(opcode)
+			}
+			c.pitch = c.V[x]
+			SetBeepFrequency(pitchFrequency(c.pitch))
+		case 0x29: // FX29 set sprite address for digit, low nibble only
+			c.I = c.FontAddress + uint16(c.V[x]&0x0F)*5
+		case 0x30: // FX30 set big sprite address for digit 0-9 (SCHIP/XO-CHIP)
+			if c.Variant == VariantChip8 {
+				return c.unknownOpcode(opcode)
+			}
+			digit := c.V[x] & 0x0F
+			if int(digit) >= len(bigFontset)/10 {
+				return c.fault("no big font sprite for digit %X", digit)
+			}
+			c.I = c.bigFontAddress() + uint16(digit)*10
 		case 0x33: // FX33 store bcd of vx
-			if c.I+2 >= 4096 {
-				fmt.Printf("BCD write out of bounds at I=%04X\n", c.I)
-				return
+			if !c.iFits(3) {
+				return c.fault("BCD write out of bounds at I=%04X", c.I)
 			}
 			value := c.V[x]
-			c.memory[c.I] = value / 100
-			c.memory[c.I+1] = (value / 10) % 10
-			c.memory[c.I+2] = value % 10
+			c.memory[c.iAddr(0)] = value / 100
+			c.memory[c.iAddr(1)] = (value / 10) % 10
+			c.memory[c.iAddr(2)] = value % 10
 		case 0x55:
-			if c.I+x >= 4096 {
-				fmt.Printf("Register dump out of bounds at I=%04X\n", c.I)
-				return
+			if !c.iFits(int(x) + 1) {
+				return c.fault("Register dump out of bounds at I=%04X", c.I)
 			}
 			for i := uint16(0); i <= x; i++ {
-				c.memory[c.I+i] = c.V[i]
+				c.memory[c.iAddr(int(i))] = c.V[i]
+			}
+			if c.Quirks.IncrementI {
+				c.I = uint16(c.iAddr(int(x) + 1))
 			}
 		case 0x65:
-			if c.I+x >= 4096 {
-				fmt.Printf("Register load out of bounds at I=%04X\n", c.I)
-				return
+			if !c.iFits(int(x) + 1) {
+				return c.fault("Register load out of bounds at I=%04X", c.I)
 			}
 			for i := uint16(0); i <= x; i++ {
-				c.V[i] = c.memory[c.I+i]
+				c.V[i] = c.memory[c.iAddr(int(i))]
 			}
+			if c.Quirks.IncrementI {
+				c.I = uint16(c.iAddr(int(x) + 1))
+			}
+		default:
+			return c.unknownOpcode(opcode)
 		}
 
 	default:
-		fmt.Printf("Unkown opcode: %04X\n", opcode)
+		return c.unknownOpcode(opcode)
+	}
+	return nil
+}
+
+// setFlag writes VF as an instruction's flag result and reports it to
+// OnFlagWrite.
+func (c *Chip8) setFlag(v byte, opcode uint16) {
+	old := c.V[0xF]
+	c.V[0xF] = v
+	if c.OnFlagWrite != nil {
+		c.OnFlagWrite(c.opPC, old, v, mnemonic(opcode))
 	}
 }
 
+// registerRange stores or loads VX..VY at I, in reverse when Y < X. I is left
+// unchanged.
+func (c *Chip8) registerRange(x, y uint16, store bool) error {
+	step := 1
+	n := int(y) - int(x)
+	if n < 0 {
+		step, n = -1, -n
+	}
+	if !c.iFits(n + 1) {
+		return c.fault("Register range out of bounds at I=%04X", c.I)
+	}
+	for i := 0; i <= n; i++ {
+		reg := int(x) + i*step
+		if store {
+			c.memory[c.iAddr(i)] = c.V[reg]
+		} else {
+			c.V[reg] = c.memory[c.iAddr(i)]
+		}
+	}
+	return nil
+}
+
+// iAddr is the address off bytes past I, wrapped within memory under the
+// IWrapsAtMemEnd quirk.
+func (c *Chip8) iAddr(off int) int {
+	addr := int(c.I) + off
+	if c.Quirks.IWrapsAtMemEnd {
+		addr %= len(c.memory)
+	}
+	return addr
+}
+
+// iFits reports whether n bytes from I can be accessed, which they always
+// can when I wraps.
+func (c *Chip8) iFits(n int) bool {
+	return c.Quirks.IWrapsAtMemEnd || int(c.I)+n <= len(c.memory)
+}
+
+// spriteSource reads sprite bytes from memory starting at addr. Data past
+// the end of memory either wraps to 0x000 or ends the sprite, per quirk, so
+// no DXYN height reads outside memory: a 15-row sprite at I=0xFF5 draws 11
+// rows by default and its last 4 from 0x000 with SpriteReadWraps.
+func (c *Chip8) spriteSource(addr int) func(off int) (byte, bool) {
+	return func(off int) (byte, bool) {
+		a := addr + off
+		if a >= len(c.memory) {
+			if !c.Quirks.SpriteReadWraps && !c.Quirks.IWrapsAtMemEnd {
+				return 0, false
+			}
+			a %= len(c.memory)
+		}
+		return c.memory[a], true
+	}
+}
+
+// drawSprite XORs (or ORs, per DrawMode) a width x height sprite into one
+// display plane at x, y, clipping or wrapping at the edges per quirk, and
+// reports whether a lit pixel was erased. Rows are width/8 bytes from src.
+func (c *Chip8) drawSprite(src func(off int) (byte, bool), x, y, width, height int, plane byte) bool {
+	w, h := c.width(), c.height()
+	rowBytes := width / 8
+	collision := false
+	for i := 0; i < rowBytes*height; i++ {
+		b, ok := src(i)
+		if !ok {
+			break
+		}
+		for bit := 0; bit < 8; bit++ {
+			if b&(0x80>>bit) == 0 {
+				continue
+			}
+			xPos := x + i%rowBytes*8 + bit
+			yPos := y + i/rowBytes
+			if (!c.Quirks.WrapX && xPos >= w) || (!c.Quirks.WrapY && yPos >= h) {
+				continue
+			}
+			xPos %= w
+			yPos %= h
+			old := c.display[xPos][yPos] & plane
+			if c.DrawMode == DrawOR {
+				if old == 0 {
+					c.display[xPos][yPos] |= plane
+					c.drawStats.PixelsToggled++
+					c.markDirty(xPos, yPos)
+				}
+				continue
+			}
+			c.display[xPos][yPos] ^= plane
+			c.drawStats.PixelsToggled++
+			c.markDirty(xPos, yPos)
+			if old != 0 {
+				collision = true
+				c.drawStats.Collisions++
+			}
+		}
+	}
+	return collision
+}
+
 func (c *Chip8) Cycle() error {
+	for n := c.keyTicks.Swap(0); n > 0; n-- {
+		c.tickKeys()
+	}
+	c.applyKeyEvent()
 	opcode, err := c.Fetch()
 	if err != nil {
 		return err
 	}
-	c.Execute(opcode)
-	return nil
+	c.Faulted = false
+	c.Cycles++
+	c.countOpcode(opcode)
+	var before traceRegs
+	if c.trace != nil {
+		before = c.traceRegs()
+	}
+	if c.CompatWarnings {
+		c.checkCompat(opcode)
+	}
+	if c.regLog != nil {
+		c.writeRegisterLine(opcode)
+	}
+	err = c.Execute(opcode)
+	if c.trace != nil {
+		c.writeTraceLine(before, opcode)
+	}
+	if c.DetectIdle {
+		c.trackIdle()
+	}
+	if c.DebugEvery > 0 && c.OnDebugTick != nil && c.Cycles%uint64(c.DebugEvery) == 0 {
+		c.OnDebugTick(c)
+	}
+	return err
 }
 
 func (c *Chip8) PrintDisplay() {
-	for y := 0; y < 32; y++ {
-		for x := 0; x < 64; x++ {
-			if c.display[x][y] {
-				fmt.Print("█")
-			} else {
-				fmt.Print(" ")
-			}
-		}
-		fmt.Println()
-	}
+	fmt.Print(c.DisplayString())
 	fmt.Println("---")
 }
 
@@ -307,32 +931,68 @@ func (c *Chip8) updateKeys() {
 	var input byte
 	fmt.Scanf("%c", &input)
 	if input >= '0' && input <= '9' {
-		c.keys[input-'0'] = true
+		c.SetKey(input-'0', true)
 	} else if input >= 'A' && input <= 'F' {
-		c.keys[input-'A'+10] = true
+		c.SetKey(input-'A'+10, true)
 	}
 }
 
 func main() {
-	emulator := Chip8{}
-	emulator.Init()
-	InitSound()
-	emulator.StartTimers()
+	subcommands := map[string]func([]string, io.Writer) error{
+		"disasm": runDisasm,
+		"golden": runGolden,
+	}
+	if len(os.Args) > 1 && subcommands[os.Args[1]] != nil {
+		if err := subcommands[os.Args[1]](os.Args[2:], os.Stdout); err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	emulator.DT = 60
-	emulator.ST = 30
+	monitor := flag.Bool("monitor", false, "run the interactive monitor on stdin")
+	hud := flag.Bool("hud", false, "print instructions and frames per second")
+	debugEvery := flag.Int("debug", 0, "print registers and the display every n cycles, 0 = never")
+	flag.Parse()
+	romPath := "assets/roms/ibm.ch8"
+	if flag.NArg() > 0 {
+		romPath = flag.Arg(0)
+	}
 
-	if err := emulator.LoadROM("assets/roms/ibm.ch8"); err != nil {
+	emulator := Chip8{DebugEvery: *debugEvery}
+	emulator.OnDebugTick = func(c *Chip8) {
+		fmt.Printf("Cycle %d: PC=%04X, V0=%02X\n", c.Cycles, c.PC, c.V[0])
+		c.PrintDisplay()
+	}
+	emulator.Init()
+
+	if err := emulator.LoadROM(romPath); err != nil {
 		fmt.Println("Error loading ROM: ", err)
 		return
 	}
+	emulator.DetectQuirks()
 
-	for i := range 1000 {
-		emulator.Cycle()
+	if *monitor {
+		if err := NewMonitor(&emulator, os.Stdin, os.Stdout).Run(); err != nil {
+			fmt.Println("Monitor error: ", err)
+		}
+		return
+	}
+
+	InitSound()
+	emulator.StartTimers()
+
+	emulator.Stats() // the first call only records the baseline
+	lastHUD := time.Now()
+	for range 1000 {
+		if err := emulator.Cycle(); err != nil {
+			fmt.Println("Error: ", err)
+		}
 		time.Sleep(2 * time.Millisecond)
-		if i%100 == 0 {
-			fmt.Printf("Cycle %d: PC=%04X, V0=%02X\n", i, emulator.PC, emulator.V[0])
-			emulator.PrintDisplay()
+		if *hud && time.Since(lastHUD) >= time.Second {
+			ips, fps := emulator.Stats()
+			fmt.Printf("IPS=%.0f FPS=%.1f\n", ips, fps)
+			lastHUD = time.Now()
 		}
 	}
 }
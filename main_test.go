@@ -0,0 +1,762 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestChip returns an initialised machine with program loaded at 0x200.
+func newTestChip(t *testing.T, program ...uint16) *Chip8 {
+	t.Helper()
+	c := &Chip8{}
+	c.Init()
+	loadProgram(t, c, program...)
+	return c
+}
+
+func loadProgram(t *testing.T, c *Chip8, program ...uint16) {
+	t.Helper()
+	data := make([]byte, 0, 2*len(program))
+	for _, op := range program {
+		data = append(data, byte(op>>8), byte(op))
+	}
+	if err := c.loadROMData(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func step(t *testing.T, c *Chip8, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := c.Step(); err != nil {
+			t.Fatalf("step %d at %03X: %v", i, c.PC, err)
+		}
+	}
+}
+
+// fakeClock is a manually advanced clock for the c.clock hook.
+type fakeClock struct{ t time.Time }
+
+func (f *fakeClock) now() time.Time { return f.t }
+
+func (f *fakeClock) advance(d time.Duration) { f.t = f.t.Add(d) }
+
+func withFakeClock(c *Chip8) *fakeClock {
+	f := &fakeClock{t: time.Unix(1000, 0)}
+	c.clock = f.now
+	return f
+}
+
+// drawAt draws the sprite rows at x, y with DXYN through V0 and V1 and
+// returns VF.
+func drawAt(t *testing.T, c *Chip8, x, y byte, rows ...byte) byte {
+	t.Helper()
+	copy(c.memory[0x300:], rows)
+	c.I = 0x300
+	c.V[0], c.V[1] = x, y
+	if err := c.ExecuteAll(0xD010 | uint16(len(rows))); err != nil {
+		t.Fatal(err)
+	}
+	return c.V[0xF]
+}
+
+func TestDXYNCollisionVectors(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		background []byte // drawn first at 0,0
+		sprite     []byte // drawn second at 0,0
+		wantVF     byte
+	}{
+		{"blank background", nil, []byte{0xFF}, 0},
+		{"disjoint pixels", []byte{0xF0}, []byte{0x0F}, 0},
+		{"one pixel overlap", []byte{0x80}, []byte{0x81}, 1},
+		{"full overlap", []byte{0xFF, 0xFF}, []byte{0xFF, 0xFF}, 1},
+		{"collision only in first row", []byte{0x80}, []byte{0x80, 0x01}, 1},
+		{"collision only in last row", []byte{0x00, 0x00, 0x01}, []byte{0x80, 0x80, 0x01}, 1},
+		{"empty sprite over pixels", []byte{0xFF}, []byte{0x00}, 0},
+	} {
+		c := newTestChip(t)
+		if tc.background != nil {
+			drawAt(t, c, 0, 0, tc.background...)
+		}
+		if vf := drawAt(t, c, 0, 0, tc.sprite...); vf != tc.wantVF {
+			t.Errorf("%s: VF = %d, want %d", tc.name, vf, tc.wantVF)
+		}
+	}
+}
+
+func TestDXYNErasesOverlap(t *testing.T) {
+	c := newTestChip(t)
+	drawAt(t, c, 0, 0, 0xC0)
+	drawAt(t, c, 0, 0, 0x60)
+	if c.display[0][0] != 1 || c.display[1][0] != 0 || c.display[2][0] != 1 {
+		t.Errorf("XOR row = %d%d%d, want 101", c.display[0][0], c.display[1][0], c.display[2][0])
+	}
+}
+
+func TestStackSize(t *testing.T) {
+	for _, size := range []int{16, 32} {
+		c := &Chip8{StackSize: size}
+		c.Init()
+		loadProgram(t, c, 0x2200) // call itself forever
+		step(t, c, size)
+		if int(c.SP) != size {
+			t.Fatalf("size %d: SP = %d after %d calls", size, c.SP, size)
+		}
+		if err := c.Step(); err == nil || !c.Faulted {
+			t.Errorf("size %d: call %d did not overflow", size, size+1)
+		}
+	}
+}
+
+func TestStackSizeClamped(t *testing.T) {
+	c := &Chip8{StackSize: 1000}
+	c.Init()
+	if c.StackSize != 255 || len(c.stack) != 255 {
+		t.Errorf("StackSize = %d, stack = %d, want 255", c.StackSize, len(c.stack))
+	}
+}
+
+func TestOddLengthROM(t *testing.T) {
+	c := newTestChip(t)
+	if err := c.loadROMData([]byte{0x60, 0x05, 0x61}); err != nil {
+		t.Fatal(err)
+	}
+	if !c.OddROM {
+		t.Fatal("OddROM not set for a 3-byte ROM")
+	}
+	step(t, c, 1)
+	if err := c.Step(); err == nil {
+		t.Errorf("fetching the trailing half instruction succeeded, V1=%d", c.V[1])
+	}
+	lines := c.DisassembleProgram(0x200)
+	if len(lines) != 2 || lines[1] != "202: 61    DB 61" {
+		t.Errorf("disassembly = %q", lines)
+	}
+}
+
+func TestArithmeticWithVFAsX(t *testing.T) {
+	for _, tc := range []struct {
+		opcode uint16
+		vf, v1 byte
+		wantVF byte // the flag, not the result
+	}{
+		{0x8F14, 0xFF, 0x01, 1}, // 0xFF+1 carries, result 0
+		{0x8F14, 0x01, 0x01, 0}, // no carry, result 2
+		{0x8F15, 0x01, 0x02, 0}, // borrow, result 0xFF
+		{0x8F15, 0x05, 0x02, 1}, // no borrow, result 3
+		{0x8F17, 0x02, 0x01, 0}, // V1-VF borrows, result 0xFF
+		{0x8F17, 0x01, 0x05, 1}, // no borrow, result 4
+		{0x8F16, 0x02, 0x00, 0}, // shifts out 0, result 1
+		{0x8F1E, 0x81, 0x00, 1}, // shifts out 1, result 2
+	} {
+		c := newTestChip(t)
+		c.Quirks.ShiftUsesVY = false
+		c.V[0xF], c.V[1] = tc.vf, tc.v1
+		if err := c.Execute(tc.opcode); err != nil {
+			t.Fatal(err)
+		}
+		if c.V[0xF] != tc.wantVF {
+			t.Errorf("%04X with VF=%02X V1=%02X: VF = %02X, want %d", tc.opcode, tc.vf, tc.v1, c.V[0xF], tc.wantVF)
+		}
+	}
+}
+
+func TestDXYNMultipleCollisionsKeepVF(t *testing.T) {
+	c := newTestChip(t)
+	drawAt(t, c, 0, 0, 0x81, 0x00, 0x18)
+	before := c.DrawStats().Collisions
+	if vf := drawAt(t, c, 0, 0, 0x81, 0x42, 0x18, 0x24); vf != 1 {
+		t.Errorf("VF = %d after four erased pixels, want 1", vf)
+	}
+	if got := c.DrawStats().Collisions - before; got != 4 {
+		t.Errorf("%d pixels erased, want 4", got)
+	}
+}
+
+func TestDXYNWrapPerAxis(t *testing.T) {
+	for _, tc := range []struct {
+		wrapX, wrapY bool
+	}{
+		{false, false}, {true, false}, {false, true}, {true, true},
+	} {
+		c := newTestChip(t)
+		c.Quirks.WrapX, c.Quirks.WrapY = tc.wrapX, tc.wrapY
+		drawAt(t, c, 60, 0, 0xFF)
+		if c.pixel(63, 0) != true || c.pixel(0, 0) != tc.wrapX {
+			t.Errorf("WrapX=%v: right edge drew (63,0)=%v (0,0)=%v", tc.wrapX, c.pixel(63, 0), c.pixel(0, 0))
+		}
+		drawAt(t, c, 10, 31, 0x80, 0x80)
+		if c.pixel(10, 31) != true || c.pixel(10, 0) != tc.wrapY {
+			t.Errorf("WrapY=%v: bottom edge drew (10,31)=%v (10,0)=%v", tc.wrapY, c.pixel(10, 31), c.pixel(10, 0))
+		}
+	}
+}
+
+func TestFetchBigEndian(t *testing.T) {
+	c := newTestChip(t)
+	c.memory[0x200], c.memory[0x201] = 0x12, 0x34
+	op, err := c.Fetch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op != 0x1234 || c.PC != 0x202 {
+		t.Errorf("Fetch = %04X with PC %03X, want 1234 with PC 202", op, c.PC)
+	}
+}
+
+func TestPeekLeavesPC(t *testing.T) {
+	c := newTestChip(t, 0x1234)
+	if op := c.Peek(); op != 0x1234 || c.PC != 0x200 {
+		t.Errorf("Peek = %04X with PC %03X, want 1234 with PC 200", op, c.PC)
+	}
+}
+
+func TestRegisterRangeXOChip(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		save uint16
+		want []byte // memory at I after the save
+	}{
+		{"ascending", 0x5132, []byte{0x11, 0x22, 0x33}},
+		{"descending", 0x5312, []byte{0x33, 0x22, 0x11}},
+	} {
+		c := newTestChip(t, tc.save, 0x6100, 0x6200, 0x6300, tc.save+1)
+		c.Variant = VariantXOChip
+		c.V[1], c.V[2], c.V[3] = 0x11, 0x22, 0x33
+		c.I = 0x300
+		step(t, c, 1)
+		if got := c.memory[0x300:0x303]; string(got) != string(tc.want) || c.I != 0x300 {
+			t.Errorf("%s save: memory % X with I %03X, want % X with I 300", tc.name, got, c.I, tc.want)
+		}
+		step(t, c, 4)
+		if c.V[1] != 0x11 || c.V[2] != 0x22 || c.V[3] != 0x33 || c.I != 0x300 {
+			t.Errorf("%s load: V1..V3 = % X with I %03X", tc.name, c.V[1:4], c.I)
+		}
+	}
+}
+
+func TestRegisterRangeNeedsXOChip(t *testing.T) {
+	c := newTestChip(t, 0x5132)
+	c.Strict = true
+	if err := c.Step(); err == nil {
+		t.Error("5XY2 accepted outside XO-CHIP")
+	}
+}
+
+func TestSoftResetKeepsDisplay(t *testing.T) {
+	c := newTestChip(t, 0x6105, 0x2206, 0x1204, 0x00EE)
+	step(t, c, 2)
+	drawAt(t, c, 0, 0, 0xF0, 0x90)
+	c.SetDelayTimer(30)
+	before := c.display
+	c.SoftReset()
+	if c.display != before {
+		t.Error("SoftReset changed the display")
+	}
+	if c.PC != 0x200 || c.V != [16]byte{} || c.I != 0 || c.SP != 0 || c.DelayTimer() != 0 {
+		t.Errorf("SoftReset left PC=%03X V=%v I=%03X SP=%d DT=%d", c.PC, c.V, c.I, c.SP, c.DelayTimer())
+	}
+	c.Reset()
+	if c.display != ([128][64]byte{}) {
+		t.Error("Reset kept the display")
+	}
+}
+
+func TestSoftResetDropsInstructionState(t *testing.T) {
+	c := newTestChip(t, 0xF50A, 0x1202)
+	c.Quirks.WaitKeyOnRelease = true
+	c.SetKey(5, true)
+	step(t, c, 1) // FX0A saw 5 pressed and now waits for its release
+	c.SoftReset()
+	c.SetKey(5, false)
+	step(t, c, 1)
+	if c.PC != 0x200 || c.V[5] != 0 {
+		t.Errorf("FX0A after SoftReset completed with the old key: PC=%03X V5=%X", c.PC, c.V[5])
+	}
+
+	c = newTestChip(t, 0x00EE)
+	if err := c.Step(); err == nil {
+		t.Fatal("00EE on an empty stack did not fault")
+	}
+	c.SoftReset()
+	if err := c.RetryLast(); err == nil {
+		t.Error("RetryLast retried a fault from before SoftReset")
+	}
+}
+
+// writeROM writes data to a file in a test temp directory and returns its path.
+func writeROM(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadROMsContiguous(t *testing.T) {
+	a := writeROM(t, "a.ch8", []byte{0x61, 0x08, 0x62})
+	b := writeROM(t, "b.ch8", []byte{0x01, 0x12, 0x04})
+	c := &Chip8{}
+	c.Init()
+	if err := c.LoadROMs(a, b); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0x61, 0x08, 0x62, 0x01, 0x12, 0x04}
+	if got := c.memory[0x200:0x206]; string(got) != string(want) || c.romLen != 6 {
+		t.Errorf("memory % X with romLen %d, want % X with 6", got, c.romLen, want)
+	}
+}
+
+func TestLoadROMsNamesOversizeFile(t *testing.T) {
+	a := writeROM(t, "a.ch8", make([]byte, 3000))
+	b := writeROM(t, "b.ch8", make([]byte, 1000))
+	c := &Chip8{}
+	c.Init()
+	err := c.LoadROMs(a, b)
+	if err == nil || !strings.Contains(err.Error(), "after "+b) {
+		t.Errorf("LoadROMs = %v, want an error naming %s", err, b)
+	}
+}
+
+func TestOnFlagWriteCarry(t *testing.T) {
+	c := newTestChip(t)
+	type write struct {
+		pc       uint16
+		old, new byte
+		op       string
+	}
+	var got []write
+	c.OnFlagWrite = func(pc uint16, old, new byte, op string) {
+		got = append(got, write{pc, old, new, op})
+	}
+	if err := c.ExecuteAll(0x60FF, 0x6102, 0x8014); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != (write{0x200, 0, 1, "8XY4"}) {
+		t.Errorf("flag writes = %+v, want one 8XY4 from 0 to 1", got)
+	}
+}
+
+func TestReloadROM(t *testing.T) {
+	path := writeROM(t, "dev.ch8", []byte{0x61, 0x01, 0x12, 0x02})
+	c := &Chip8{}
+	c.Init()
+	if err := c.LoadROM(path); err != nil {
+		t.Fatal(err)
+	}
+	step(t, c, 2)
+	if err := os.WriteFile(path, []byte{0x62, 0x02, 0x12, 0x04, 0x00, 0xE0}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ReloadROM(); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0x62, 0x02, 0x12, 0x04, 0x00, 0xE0}
+	if got := c.memory[0x200:0x206]; string(got) != string(want) || c.PC != 0x200 || c.V[1] != 0 {
+		t.Errorf("after reload memory % X PC=%03X V1=%d", got, c.PC, c.V[1])
+	}
+}
+
+func TestReloadROMWithoutFile(t *testing.T) {
+	c := newTestChip(t, 0x1200)
+	if err := c.ReloadROM(); err == nil {
+		t.Error("ReloadROM with no ROM file succeeded")
+	}
+}
+
+// litRows returns which of the first n display rows have a pixel lit in
+// columns 0-7.
+func litRows(c *Chip8, n int) []bool {
+	rows := make([]bool, n)
+	for y := range rows {
+		for x := 0; x < 8; x++ {
+			rows[y] = rows[y] || c.pixel(x, y)
+		}
+	}
+	return rows
+}
+
+func TestDXYNNearMemoryEnd(t *testing.T) {
+	for _, wraps := range []bool{false, true} {
+		c := newTestChip(t)
+		c.Quirks.SpriteReadWraps = wraps
+		c.memory[0xFFE], c.memory[0xFFF], c.memory[0x000] = 0xFF, 0xFF, 0x80
+		c.I = 0xFFE
+		if err := c.ExecuteAll(0xD01F); err != nil {
+			t.Fatalf("wraps=%v: %v", wraps, err)
+		}
+		rows := litRows(c, 15)
+		if !rows[0] || !rows[1] {
+			t.Errorf("wraps=%v: rows from 0xFFE and 0xFFF not drawn", wraps)
+		}
+		// past 0xFFF the sprite continues from 0x000 only when wrapping
+		if rows[2] != wraps {
+			t.Errorf("wraps=%v: row 2 drawn=%v", wraps, rows[2])
+		}
+	}
+}
+
+func TestFontAddress(t *testing.T) {
+	c := &Chip8{FontAddress: 0x50}
+	c.Init()
+	loadProgram(t, c, 0x6007, 0xF029)
+	step(t, c, 2)
+	if c.I != 0x50+7*5 {
+		t.Fatalf("FX29 set I to %03X, want %03X", c.I, 0x50+7*5)
+	}
+	if got := c.memory[c.I : c.I+5]; string(got) != string(fontset[7*5:8*5]) {
+		t.Errorf("digit 7 at I = % X, want % X", got, fontset[7*5:8*5])
+	}
+	if c.memory[0] != 0 {
+		t.Error("fontset also copied to 0x000")
+	}
+}
+
+func TestFontAddressOutOfRange(t *testing.T) {
+	log := &captureLogger{}
+	c := &Chip8{FontAddress: 0x1C0, Logger: log}
+	c.Init()
+	if c.FontAddress != 0 {
+		t.Errorf("FontAddress = %03X, want 000", c.FontAddress)
+	}
+	if len(log.lines) != 1 || !strings.Contains(log.lines[0], "1C0") {
+		t.Errorf("logged %q, want one entry naming 1C0", log.lines)
+	}
+	log.lines = nil
+	c = &Chip8{FontAddress: 0x50, Logger: log}
+	c.Init()
+	if len(log.lines) != 0 {
+		t.Errorf("valid font address logged %q", log.lines)
+	}
+}
+
+func TestShiftVFIntoItself(t *testing.T) {
+	for _, usesVY := range []bool{false, true} {
+		for _, tc := range []struct {
+			opcode uint16
+			vf     byte
+			want   byte // the shifted-out bit, not the shift result
+		}{
+			{0x8FF6, 0x81, 1},
+			{0x8FF6, 0x82, 0},
+			{0x8FFE, 0x81, 1},
+			{0x8FFE, 0x41, 0},
+		} {
+			c := newTestChip(t)
+			c.Quirks.ShiftUsesVY = usesVY
+			c.V[0xF] = tc.vf
+			if err := c.ExecuteAll(tc.opcode); err != nil {
+				t.Fatal(err)
+			}
+			if c.V[0xF] != tc.want {
+				t.Errorf("%04X with VF=%02X ShiftUsesVY=%v: VF = %02X, want %d",
+					tc.opcode, tc.vf, usesVY, c.V[0xF], tc.want)
+			}
+		}
+	}
+}
+
+func TestDrawModeXORvsOR(t *testing.T) {
+	for _, tc := range []struct {
+		mode      DrawMode
+		wantPixel bool // (0,0), drawn by both sprites
+		wantVF    byte
+	}{
+		{DrawXOR, false, 1},
+		{DrawOR, true, 0},
+	} {
+		c := newTestChip(t)
+		c.DrawMode = tc.mode
+		drawAt(t, c, 0, 0, 0xC0)
+		vf := drawAt(t, c, 0, 0, 0xA0)
+		if c.pixel(0, 0) != tc.wantPixel || !c.pixel(1, 0) || !c.pixel(2, 0) || vf != tc.wantVF {
+			t.Errorf("mode %d: pixels %v %v %v VF=%d", tc.mode, c.pixel(0, 0), c.pixel(1, 0), c.pixel(2, 0), vf)
+		}
+	}
+}
+
+func TestMemFillByte(t *testing.T) {
+	c := &Chip8{MemFillByte: 0xFF}
+	c.Init()
+	loadProgram(t, c, 0x6001, 0x1202)
+	if got := c.memory[0]; got != fontset[0] {
+		t.Errorf("fontset overwritten: memory[0] = %02X", got)
+	}
+	if got := c.memory[0x201]; got != 0x01 {
+		t.Errorf("ROM overwritten: memory[0x201] = %02X", got)
+	}
+	for _, addr := range []int{0x1FF, 0x204, 0x800, 0xFFF} {
+		if c.memory[addr] != 0xFF {
+			t.Errorf("memory[%03X] = %02X, want the fill byte FF", addr, c.memory[addr])
+		}
+	}
+}
+
+func TestDXYNPlaneCollisions(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		before byte   // plane bits at (0,0)
+		planes uint16 // FN01 selection
+		data   []byte // one row per selected plane, plane 1 first
+		want   byte   // plane bits at (0,0) after
+		wantVF byte
+	}{
+		{"plane 2 over plane 1", 1, 2, []byte{0x80}, 3, 0},
+		{"plane 1 over plane 1", 1, 1, []byte{0x80}, 0, 1},
+		{"both planes, hit in plane 2 only", 2, 3, []byte{0x00, 0x80}, 0, 1},
+		{"both planes, hit in plane 1 only", 1, 3, []byte{0x80, 0x00}, 0, 1},
+		{"both planes, no overlap", 1, 3, []byte{0x00, 0x80}, 3, 0},
+		{"both planes, hit in both", 3, 3, []byte{0x80, 0x80}, 0, 1},
+	} {
+		c := newTestChip(t)
+		c.Variant = VariantXOChip
+		c.display[0][0] = tc.before
+		copy(c.memory[0x300:], tc.data)
+		c.I = 0x300
+		if err := c.ExecuteAll(0xF001|tc.planes<<8, 0xD011); err != nil {
+			t.Fatal(err)
+		}
+		if c.display[0][0] != tc.want || c.V[0xF] != tc.wantVF {
+			t.Errorf("%s: planes %02b VF=%d, want %02b and %d", tc.name, c.display[0][0], c.V[0xF], tc.want, tc.wantVF)
+		}
+	}
+}
+
+func TestFX29MasksDigit(t *testing.T) {
+	c := newTestChip(t, 0x601A, 0xF029)
+	step(t, c, 2)
+	if c.I != c.FontAddress+0xA*5 {
+		t.Errorf("FX29 with VX=1A set I to %03X, want digit A at %03X", c.I, c.FontAddress+0xA*5)
+	}
+}
+
+func TestFX30BigDigits(t *testing.T) {
+	c := newTestChip(t)
+	c.Variant = VariantSCHIP
+	for digit := byte(0); digit < 10; digit++ {
+		c.V[0] = digit
+		if err := c.ExecuteAll(0xF030); err != nil {
+			t.Fatal(err)
+		}
+		want := c.bigFontAddress() + uint16(digit)*10
+		if c.I != want || string(c.memory[c.I:c.I+10]) != string(bigFontset[digit*10:digit*10+10]) {
+			t.Errorf("FX30 digit %d: I=%03X, want %03X holding its big sprite", digit, c.I, want)
+		}
+	}
+	if c.bigFontAddress() < c.FontAddress+uint16(len(fontset)) {
+		t.Error("big font overlaps the small font")
+	}
+	c.V[0] = 0xA
+	if err := c.ExecuteAll(0xF030); err == nil {
+		t.Error("FX30 accepted digit A")
+	}
+}
+
+func TestIWrapsAtMemEnd(t *testing.T) {
+	for _, wraps := range []bool{false, true} {
+		c := newTestChip(t)
+		c.Quirks.IWrapsAtMemEnd = wraps
+		c.I = 0xFFE
+		c.V[0], c.V[1], c.V[2] = 4, 0xAA, 0xBB
+		if err := c.ExecuteAll(0xF01E); err != nil {
+			t.Fatal(err)
+		}
+		wantI := uint16(0x1002)
+		if wraps {
+			wantI = 0x002
+		}
+		if c.I != wantI || c.V[0xF] != 1 {
+			t.Errorf("wraps=%v: FX1E gave I=%03X VF=%d, want %03X and 1", wraps, c.I, c.V[0xF], wantI)
+		}
+
+		c = newTestChip(t)
+		c.Quirks.IWrapsAtMemEnd = wraps
+		c.I = 0xFFE
+		c.V[0], c.V[1], c.V[2] = 0x11, 0x22, 0x33
+		err := c.ExecuteAll(0xF255)
+		if !wraps {
+			if err == nil || c.memory[0xFFE] != 0 {
+				t.Errorf("FX55 past memory end: err=%v, memory written=%v", err, c.memory[0xFFE] != 0)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.memory[0xFFE] != 0x11 || c.memory[0xFFF] != 0x22 || c.memory[0x000] != 0x33 {
+			t.Errorf("wrapped FX55 wrote % X and %02X", c.memory[0xFFE:], c.memory[0])
+		}
+	}
+}
+
+// litCount returns the number of pixels with any plane bit set.
+func litCount(c *Chip8) int {
+	n := 0
+	for x := range c.display {
+		for y := range c.display[x] {
+			if c.display[x][y] != 0 {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestDXYNWidthCombinations(t *testing.T) {
+	full := make([]byte, 64)
+	for i := range full {
+		full[i] = 0xFF
+	}
+	for _, tc := range []struct {
+		name    string
+		variant Variant
+		planes  byte
+		wrapX   bool
+		x       byte
+		opcode  uint16
+		want    int // lit pixels after the draw
+	}{
+		{"chip-8 D010 draws nothing", VariantChip8, 1, true, 0, 0xD010, 0},
+		{"8x15", VariantChip8, 1, true, 0, 0xD01F, 8 * 15},
+		{"schip 16x16", VariantSCHIP, 1, true, 0, 0xD010, 16 * 16},
+		{"schip 16x16 clipped at the right edge", VariantSCHIP, 1, false, 56, 0xD010, 8 * 16},
+		{"schip 16x16 wrapped at the right edge", VariantSCHIP, 1, true, 56, 0xD010, 16 * 16},
+		{"xo-chip 16x16 on both planes", VariantXOChip, 3, true, 0, 0xD010, 16 * 16},
+	} {
+		c := newTestChip(t)
+		c.Variant = tc.variant
+		c.planes = tc.planes
+		c.Quirks.WrapX = tc.wrapX
+		copy(c.memory[0x300:], full)
+		c.I = 0x300
+		c.V[0] = tc.x
+		if err := c.ExecuteAll(tc.opcode); err != nil {
+			t.Fatal(err)
+		}
+		if got := litCount(c); got != tc.want || c.V[0xF] != 0 {
+			t.Errorf("%s: %d lit pixels VF=%d, want %d and 0", tc.name, got, c.V[0xF], tc.want)
+		}
+		if tc.planes == 3 && c.display[0][0] != 3 {
+			t.Errorf("%s: planes at (0,0) = %02b, want both", tc.name, c.display[0][0])
+		}
+		// drawing the same sprite again erases it and reports the collision
+		if err := c.ExecuteAll(tc.opcode); err != nil {
+			t.Fatal(err)
+		}
+		if wantVF := byte(min(tc.want, 1)); litCount(c) != 0 || c.V[0xF] != wantVF {
+			t.Errorf("%s: redraw left %d pixels VF=%d, want 0 and %d", tc.name, litCount(c), c.V[0xF], wantVF)
+		}
+	}
+}
+
+func TestDXYNFifteenRowsAtFF5(t *testing.T) {
+	for _, wraps := range []bool{false, true} {
+		c := newTestChip(t)
+		c.Quirks.SpriteReadWraps = wraps
+		for a := 0xFF5; a <= 0xFFF; a++ {
+			c.memory[a] = 0x80
+		}
+		copy(c.memory[0:], []byte{0x80, 0x80, 0x80, 0x80})
+		c.I = 0xFF5
+		if err := c.ExecuteAll(0xD01F); err != nil {
+			t.Fatalf("wraps=%v: %v", wraps, err)
+		}
+		want := 11
+		if wraps {
+			want = 15
+		}
+		if got := litCount(c); got != want {
+			t.Errorf("wraps=%v: drew %d rows, want %d", wraps, got, want)
+		}
+	}
+}
+
+func TestNineXYN(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		for _, opcode := range []uint16{0x9120, 0x9121} {
+			c := newTestChip(t, 0x6101, opcode)
+			c.Strict = strict
+			step(t, c, 1)
+			err := c.Step()
+			if opcode == 0x9121 && strict {
+				var unknown *ErrUnknownOpcode
+				if !errors.As(err, &unknown) || unknown.Opcode != 0x9121 {
+					t.Errorf("strict 9121: err = %v, want ErrUnknownOpcode", err)
+				}
+				continue
+			}
+			if err != nil || c.PC != 0x206 {
+				t.Errorf("strict=%v %04X: err=%v PC=%03X, want the skip to 206", strict, opcode, err, c.PC)
+			}
+		}
+	}
+}
+
+func TestSkipOverXOChipLongLoad(t *testing.T) {
+	for _, tc := range []struct {
+		variant Variant
+		wantPC  uint16
+	}{
+		{VariantChip8, 0x204},
+		{VariantXOChip, 0x206},
+	} {
+		c := newTestChip(t, 0x3000, 0xF000, 0x1234)
+		c.Variant = tc.variant
+		step(t, c, 1)
+		if c.PC != tc.wantPC {
+			t.Errorf("%s: skip over F000 landed at %03X, want %03X", tc.variant, c.PC, tc.wantPC)
+		}
+	}
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestLoadGzipROM(t *testing.T) {
+	rom := []byte{0x61, 0x08, 0x12, 0x02}
+	path := writeROM(t, "game.bin", gzipBytes(t, rom)) // detected by magic, not name
+	c := &Chip8{}
+	c.Init()
+	if err := c.LoadROM(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.memory[0x200:0x204]; !bytes.Equal(got, rom) || c.romLen != len(rom) {
+		t.Errorf("loaded % X with romLen %d, want % X", got, c.romLen, rom)
+	}
+}
+
+func TestLoadGzipROMTooLarge(t *testing.T) {
+	path := writeROM(t, "big.ch8.gz", gzipBytes(t, make([]byte, 8192)))
+	c := &Chip8{}
+	c.Init()
+	if err := c.LoadROM(path); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("LoadROM = %v, want a size error", err)
+	}
+}
+
+func TestLoadCorruptGzipROM(t *testing.T) {
+	path := writeROM(t, "bad.gz", []byte{0x1f, 0x8b, 0x00})
+	c := &Chip8{}
+	c.Init()
+	if err := c.LoadROM(path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("LoadROM = %v, want an error naming %s", err, path)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const memMapCell = 16 // bytes per map cell
+
+// MemoryMap renders the 4KB address space, one character per 16 bytes:
+// F fontset, R ROM, S stack return address, I index register, P program
+// counter, . unused. Markers are taken from the live state on every call.
+func (c *Chip8) MemoryMap() string {
+	cells := make([]byte, len(c.memory)/memMapCell)
+	for i := range cells {
+		cells[i] = '.'
+	}
+	mark := func(from, to int, ch byte) {
+		for addr := from; addr < to && addr < len(c.memory); addr += memMapCell {
+			cells[addr/memMapCell] = ch
+		}
+	}
+	mark(int(c.FontAddress), int(c.FontAddress)+len(fontset)+len(bigFontset), 'F')
+	mark(0x200, 0x200+c.romLen, 'R')
+	for _, ret := range c.stack[:c.SP] {
+		mark(int(ret), int(ret)+1, 'S')
+	}
+	mark(int(c.I), int(c.I)+1, 'I')
+	mark(int(c.PC), int(c.PC)+1, 'P')
+
+	var b strings.Builder
+	for row := 0; row < len(cells); row += 64 {
+		fmt.Fprintf(&b, "%03X %s\n", row*memMapCell, cells[row:row+64])
+	}
+	if c.romLen > 0 {
+		fmt.Fprintf(&b, "ROM 200-%03X ", 0x200+c.romLen-1)
+	}
+	fmt.Fprintf(&b, "PC %03X I %03X", c.PC, c.I)
+	for i, ret := range c.stack[:c.SP] {
+		fmt.Fprintf(&b, " S%d %03X", i, ret)
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
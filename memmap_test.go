@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// memMapCellAt returns the MemoryMap character that covers addr.
+func memMapCellAt(t *testing.T, m string, addr int) byte {
+	t.Helper()
+	lines := strings.Split(m, "\n")
+	cell := addr / memMapCell
+	line := lines[cell/64]
+	return line[len("000 ")+cell%64]
+}
+
+func TestMemoryMapMarkers(t *testing.T) {
+	c := newTestChip(t, make([]uint16, 32)...) // ROM 0x200-0x23F
+	c.PC = 0x230
+	c.I = 0x500
+	m := c.MemoryMap()
+	for _, tc := range []struct {
+		addr int
+		want byte
+	}{
+		{int(c.FontAddress), 'F'},
+		{0x1F0, '.'},
+		{0x200, 'R'},
+		{0x220, 'R'},
+		{0x230, 'P'},
+		{0x240, '.'},
+		{0x500, 'I'},
+		{0xFF0, '.'},
+	} {
+		if got := memMapCellAt(t, m, tc.addr); got != tc.want {
+			t.Errorf("cell at %03X = %c, want %c", tc.addr, got, tc.want)
+		}
+	}
+	if !strings.Contains(m, "ROM 200-23F PC 230 I 500\n") {
+		t.Errorf("summary line missing from:\n%s", m)
+	}
+}
+
+func TestMemoryMapStack(t *testing.T) {
+	c := newTestChip(t, 0x2300)
+	step(t, c, 1)
+	m := c.MemoryMap()
+	if got := memMapCellAt(t, m, 0x202); got != 'S' {
+		t.Errorf("return address cell = %c, want S", got)
+	}
+	if !strings.Contains(m, " S0 202\n") {
+		t.Errorf("stack entry missing from:\n%s", m)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	monitorRunLimit  = 1000000
+	monitorUndoDepth = 256
+)
+
+// Monitor is a line-based debugger reading commands from in:
+// step [n], undo, run, break <addr>, regs, set <reg> <val>, mem <addr> <len>,
+// poke <addr> <byte>..., disasm <addr> <n>, reset, quit. Addresses and values
+// are hex.
+type Monitor struct {
+	c   *Chip8
+	in  io.Reader
+	out io.Writer
+}
+
+func NewMonitor(c *Chip8, in io.Reader, out io.Writer) *Monitor {
+	if c.UndoDepth == 0 {
+		c.UndoDepth = monitorUndoDepth
+	}
+	return &Monitor{c: c, in: in, out: out}
+}
+
+func (m *Monitor) Run() error {
+	m.printNext()
+	scanner := bufio.NewScanner(m.in)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "quit" || fields[0] == "q" {
+			return nil
+		}
+		if err := m.exec(fields[0], fields[1:]); err != nil {
+			fmt.Fprintln(m.out, "error:", err)
+		}
+	}
+	return scanner.Err()
+}
+
+func (m *Monitor) exec(cmd string, args []string) error {
+	switch cmd {
+	case "step", "s":
+		n := 1
+		if len(args) > 0 {
+			v, err := strconv.Atoi(args[0])
+			if err != nil {
+				return err
+			}
+			n = v
+		}
+		for i := 0; i < n; i++ {
+			if err := m.c.Step(); err != nil {
+				return err
+			}
+		}
+		m.printNext()
+	case "undo", "u":
+		if err := m.c.Undo(); err != nil {
+			return err
+		}
+		m.printNext()
+	case "run", "r":
+		r := m.c.Run(monitorRunLimit)
+		if r.Err != nil {
+			return r.Err
+		}
+		if r.Breakpoint {
+			fmt.Fprintf(m.out, "breakpoint at %03X\n", m.c.PC)
+		}
+		m.printNext()
+	case "break", "b":
+		addr, err := monitorArg(args, 0, 16)
+		if err != nil {
+			return err
+		}
+		m.c.SetBreakpoint(uint16(addr))
+		fmt.Fprintf(m.out, "breakpoint set at %03X\n", addr)
+	case "regs":
+		m.printRegs()
+	case "set":
+		if len(args) < 1 {
+			return fmt.Errorf("missing register name")
+		}
+		val, err := monitorArg(args, 1, 16)
+		if err != nil {
+			return err
+		}
+		if err := m.c.SetRegister(args[0], uint16(val)); err != nil {
+			return err
+		}
+		m.printRegs()
+	case "mem":
+		addr, err := monitorArg(args, 0, 16)
+		if err != nil {
+			return err
+		}
+		n, err := monitorArg(args, 1, 10)
+		if err != nil {
+			return err
+		}
+		data, err := m.c.ReadRange(uint16(addr), int(n))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < len(data); i += 16 {
+			end := min(i+16, len(data))
+			fmt.Fprintf(m.out, "%03X: % X\n", int(addr)+i, data[i:end])
+		}
+	case "poke":
+		addr, err := monitorArg(args, 0, 16)
+		if err != nil {
+			return err
+		}
+		if len(args) < 2 {
+			return fmt.Errorf("missing bytes to write")
+		}
+		data := make([]byte, len(args)-1)
+		for i := range data {
+			v, err := monitorArg(args, i+1, 16)
+			if err != nil {
+				return err
+			}
+			if v > 0xFF {
+				return fmt.Errorf("byte %X out of range", v)
+			}
+			data[i] = byte(v)
+		}
+		if err := m.c.WriteMem(uint16(addr), data); err != nil {
+			return err
+		}
+		m.printNext()
+	case "disasm", "d":
+		addr, err := monitorArg(args, 0, 16)
+		if err != nil {
+			return err
+		}
+		n, err := monitorArg(args, 1, 10)
+		if err != nil {
+			return err
+		}
+		for _, line := range m.c.Disassemble(uint16(addr), int(n)) {
+			fmt.Fprintln(m.out, line)
+		}
+	case "reset":
+		m.c.Reset()
+		m.printNext()
+	default:
+		return fmt.Errorf("unknown command: %s", cmd)
+	}
+	return nil
+}
+
+func (m *Monitor) printRegs() {
+	s := m.c.State()
+	fmt.Fprintf(m.out, "PC=%03X I=%03X SP=%X DT=%02X ST=%02X\n", s.PC, s.I, s.SP, s.DT, s.ST)
+	for i, v := range s.V {
+		fmt.Fprintf(m.out, "V%X=%02X", i, v)
+		if i%8 == 7 {
+			fmt.Fprintln(m.out)
+		} else {
+			fmt.Fprint(m.out, " ")
+		}
+	}
+}
+
+func (m *Monitor) printNext() {
+	pc, opcode, asm := m.c.Preview()
+	fmt.Fprintf(m.out, "%03X: %04X  %s\n", pc, opcode, asm)
+}
+
+func monitorArg(args []string, i int, base int) (uint64, error) {
+	if i >= len(args) {
+		return 0, fmt.Errorf("missing argument %d", i+1)
+	}
+	return strconv.ParseUint(strings.TrimPrefix(args[i], "0x"), base, 16)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func runMonitor(t *testing.T, c *Chip8, script string) string {
+	t.Helper()
+	var out strings.Builder
+	if err := NewMonitor(c, strings.NewReader(script), &out).Run(); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestMonitorStepAndRegs(t *testing.T) {
+	c := newTestChip(t, 0x6142, 0xA123, 0x1204)
+	out := runMonitor(t, c, "step 2\nregs\nquit\nstep\n")
+	want := "200: 6142  LD V1, 42\n" +
+		"204: 1204  JP 204\n" +
+		"PC=204 I=123 SP=0 DT=00 ST=00\n" +
+		"V0=00 V1=42 V2=00 V3=00 V4=00 V5=00 V6=00 V7=00\n" +
+		"V8=00 V9=00 VA=00 VB=00 VC=00 VD=00 VE=00 VF=00\n"
+	if out != want {
+		t.Errorf("output:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestMonitorSetPokeUndo(t *testing.T) {
+	c := newTestChip(t, 0x6001, 0x6002)
+	out := runMonitor(t, c, "poke 202 63 07\nstep 2\nundo\nset v0 ff\nbogus\n")
+	if c.V[3] != 0 || c.PC != 0x202 || c.V[0] != 0xFF {
+		t.Errorf("PC=%03X V0=%02X V3=%02X", c.PC, c.V[0], c.V[3])
+	}
+	if c.memory[0x202] != 0x63 {
+		t.Error("poke did not write memory")
+	}
+	if !strings.Contains(out, "202: 6307  LD V3, 07\n") {
+		t.Errorf("poke did not show the patched instruction:\n%s", out)
+	}
+	if !strings.Contains(out, "error: unknown command: bogus\n") {
+		t.Errorf("missing error for an unknown command:\n%s", out)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import "strings"
+
+// DisableOpcode turns every opcode matching the mnemonic pattern, e.g.
+// "DXYN", into a no-op, or a fault when DisabledOpsFault is set.
+func (c *Chip8) DisableOpcode(pattern string) {
+	if c.disabledOps == nil {
+		c.disabledOps = make(map[string]bool)
+	}
+	c.disabledOps[strings.ToUpper(pattern)] = true
+}
+
+func (c *Chip8) EnableOpcode(pattern string) {
+	delete(c.disabledOps, strings.ToUpper(pattern))
+}
+
+// opcodeDisabled reports whether opcode has been switched off, and the
+// error to return for it.
+func (c *Chip8) opcodeDisabled(opcode uint16) (bool, error) {
+	if len(c.disabledOps) == 0 {
+		return false, nil
+	}
+	m := mnemonic(opcode)
+	if !c.disabledOps[m] {
+		return false, nil
+	}
+	if c.DisabledOpsFault {
+		return true, c.fault("%s is disabled", m)
+	}
+	return true, nil
+}
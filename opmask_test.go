@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func TestDisableOpcodeSkips(t *testing.T) {
+	c := newTestChip(t, 0x6005, 0x6107)
+	c.DisableOpcode("6xnn")
+	step(t, c, 2)
+	if c.V[0] != 0 || c.V[1] != 0 || c.PC != 0x204 {
+		t.Errorf("disabled 6XNN ran: V0=%d V1=%d PC=%03X", c.V[0], c.V[1], c.PC)
+	}
+	c.EnableOpcode("6XNN")
+	c.PC = 0x200
+	step(t, c, 1)
+	if c.V[0] != 5 {
+		t.Errorf("re-enabled 6XNN did not run: V0=%d", c.V[0])
+	}
+}
+
+func TestDisableOpcodeFault(t *testing.T) {
+	c := newTestChip(t, 0x00E0)
+	c.DisableOpcode("00E0")
+	c.DisabledOpsFault = true
+	err := c.Step()
+	if err == nil || !c.Faulted {
+		t.Fatalf("err=%v Faulted=%v", err, c.Faulted)
+	}
+	if want := "200: 00E0 is disabled"; err.Error() != want {
+		t.Errorf("err = %q, want %q", err, want)
+	}
+}
+
+func TestDisableDXYNRunsBlind(t *testing.T) {
+	c := &Chip8{}
+	c.Init()
+	if err := c.LoadEmbeddedROM("ibm"); err != nil {
+		t.Fatal(err)
+	}
+	c.DisableOpcode("DXYN")
+	for i := 0; i < 200; i++ {
+		if err := c.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.display != ([128][64]byte{}) {
+		t.Error("display drawn with DXYN disabled")
+	}
+	if c.PC != 0x228 {
+		t.Errorf("IBM logo did not reach its final loop, PC=%03X", c.PC)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import "slices"
+
+// Pair steps two machines in lockstep, typically the same ROM under two
+// quirk configurations, to find where they first disagree.
+type Pair struct {
+	A, B *Chip8
+}
+
+// Run steps both machines up to maxCycles times and returns the 1-based
+// cycle after which their registers, stack or display first differ, with
+// the difference, or 0 if they never diverge. An error from either machine
+// stops the run.
+func (p *Pair) Run(maxCycles int) (int, SnapshotDiff, error) {
+	for i := 1; i <= maxCycles; i++ {
+		if err := p.A.Step(); err != nil {
+			return i, SnapshotDiff{}, err
+		}
+		if err := p.B.Step(); err != nil {
+			return i, SnapshotDiff{}, err
+		}
+		if p.diverged() {
+			d, err := p.diff()
+			return i, d, err
+		}
+	}
+	return 0, SnapshotDiff{}, nil
+}
+
+func (p *Pair) diverged() bool {
+	a, b := p.A.State(), p.B.State()
+	return a.PC != b.PC || a.I != b.I || a.SP != b.SP || a.V != b.V ||
+		a.DT != b.DT || a.ST != b.ST || !slices.Equal(a.Stack, b.Stack) ||
+		!p.A.DisplayEqual(p.B)
+}
+
+func (p *Pair) diff() (SnapshotDiff, error) {
+	a, err := p.A.Snapshot()
+	if err != nil {
+		return SnapshotDiff{}, err
+	}
+	b, err := p.B.Snapshot()
+	if err != nil {
+		return SnapshotDiff{}, err
+	}
+	return DiffSnapshots(a, b)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPairDivergesOnShiftQuirk(t *testing.T) {
+	program := []uint16{0x6003, 0x6105, 0x8016, 0x1206}
+	a, b := newTestChip(t, program...), newTestChip(t, program...)
+	a.Quirks.ShiftUsesVY = false
+	b.Quirks.ShiftUsesVY = true
+	p := Pair{A: a, B: b}
+	cycle, d, err := p.Run(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cycle != 3 || !slices.Equal(d.Registers, []string{"V0"}) {
+		t.Errorf("Run = %d, %+v, want a V0 divergence at cycle 3", cycle, d)
+	}
+}
+
+func TestPairNoDivergence(t *testing.T) {
+	program := []uint16{0x6003, 0x7001, 0x1202}
+	p := Pair{A: newTestChip(t, program...), B: newTestChip(t, program...)}
+	if cycle, _, err := p.Run(50); cycle != 0 || err != nil {
+		t.Errorf("Run = %d, %v, want no divergence", cycle, err)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import "math"
+
+// stepPhosphor advances the afterglow model by one 60Hz frame: lit pixels
+// are at full intensity and unlit ones decay by exp(-t/PhosphorDecay), like
+// a CRT's phosphor. The emulated display itself is untouched.
+func (c *Chip8) stepPhosphor() {
+	if c.PhosphorDecay <= 0 {
+		return
+	}
+	k := math.Exp(-1.0 / 60 / c.PhosphorDecay.Seconds())
+	for x := range c.phosphor {
+		for y := range c.phosphor[x] {
+			if c.display[x][y]&0x3 != 0 {
+				c.phosphor[x][y] = 1
+			} else {
+				c.phosphor[x][y] *= k
+			}
+		}
+	}
+}
+
+// Intensity returns a pixel's brightness from 0 to 1 for renderers: 0 or 1
+// from the display, or the phosphor afterglow when PhosphorDecay is set.
+func (c *Chip8) Intensity(x, y int) float64 {
+	if c.PhosphorDecay <= 0 {
+		if c.presented(x, y) {
+			return 1
+		}
+		return 0
+	}
+	x, y = c.orient(x, y)
+	return c.phosphor[x][y]
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPhosphorDecayCurve(t *testing.T) {
+	c := newTestChip(t, 0x1200)
+	c.PhosphorDecay = 100 * time.Millisecond
+	c.display[3][4] = 1
+	if err := c.RunFrame(); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Intensity(3, 4); got != 1 {
+		t.Fatalf("lit pixel intensity = %v, want 1", got)
+	}
+	c.display[3][4] = 0
+	for n := 1; n <= 30; n++ {
+		if err := c.RunFrame(); err != nil {
+			t.Fatal(err)
+		}
+		want := math.Exp(-float64(n) / 60 / 0.1)
+		if got := c.Intensity(3, 4); math.Abs(got-want) > 1e-9 {
+			t.Fatalf("intensity %d frames after turning off = %v, want %v", n, got, want)
+		}
+	}
+	if c.Intensity(0, 0) != 0 {
+		t.Error("never-lit pixel has an afterglow")
+	}
+}
+
+func TestIntensityWithoutPhosphor(t *testing.T) {
+	c := newTestChip(t)
+	drawAt(t, c, 3, 4, 0x80)
+	if c.Intensity(3, 4) != 1 || c.Intensity(4, 4) != 0 {
+		t.Errorf("Intensity = %v and %v, want 1 and 0", c.Intensity(3, 4), c.Intensity(4, 4))
+	}
+}
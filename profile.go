@@ -0,0 +1,124 @@
+package main
+
+// mnemonic returns the opcode pattern name, e.g. "8XY4", or "UNKNOWN".
+func mnemonic(opcode uint16) string {
+	switch opcode & 0xF000 {
+	case 0x0000:
+		switch opcode {
+		case 0x00E0:
+			return "00E0"
+		case 0x00EE:
+			return "00EE"
+		case 0x00FE:
+			return "00FE"
+		case 0x00FF:
+			return "00FF"
+		}
+		if opcode&0x0F00 != 0 {
+			return "0NNN"
+		}
+	case 0x1000:
+		return "1NNN"
+	case 0x2000:
+		return "2NNN"
+	case 0x3000:
+		return "3XNN"
+	case 0x4000:
+		return "4XNN"
+	case 0x5000:
+		switch opcode & 0x000F {
+		case 0x0:
+			return "5XY0"
+		case 0x2:
+			return "5XY2"
+		case 0x3:
+			return "5XY3"
+		}
+	case 0x6000:
+		return "6XNN"
+	case 0x7000:
+		return "7XNN"
+	case 0x8000:
+		switch opcode & 0x000F {
+		case 0x0, 0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, 0xE:
+			return "8XY" + string("0123456789ABCDEF"[opcode&0x000F])
+		}
+	case 0x9000:
+		return "9XY0"
+	case 0xA000:
+		return "ANNN"
+	case 0xB000:
+		return "BNNN"
+	case 0xC000:
+		return "CXNN"
+	case 0xD000:
+		return "DXYN"
+	case 0xE000:
+		switch opcode & 0x00FF {
+		case 0x9E:
+			return "EX9E"
+		case 0xA1:
+			return "EXA1"
+		}
+	case 0xF000:
+		switch opcode & 0x00FF {
+		case 0x01:
+			return "FN01"
+		case 0x07:
+			return "FX07"
+		case 0x0A:
+			return "FX0A"
+		case 0x15:
+			return "FX15"
+		case 0x18:
+			return "FX18"
+		case 0x1E:
+			return "FX1E"
+		case 0x29:
+			return "FX29"
+		case 0x30:
+			return "FX30"
+		case 0x3A:
+			return "FX3A"
+		case 0x33:
+			return "FX33"
+		case 0x55:
+			return "FX55"
+		case 0x65:
+			return "FX65"
+		}
+	}
+	return "UNKNOWN"
+}
+
+func (c *Chip8) countOpcode(opcode uint16) {
+	if c.opCounts == nil {
+		c.opCounts = make(map[string]uint64)
+	}
+	c.opCounts[mnemonic(opcode)]++
+}
+
+// OpcodeCounts returns how often each mnemonic has executed.
+func (c *Chip8) OpcodeCounts() map[string]uint64 {
+	counts := make(map[string]uint64, len(c.opCounts))
+	for k, v := range c.opCounts {
+		counts[k] = v
+	}
+	return counts
+}
+
+// CoverageReport runs a ROM for up to maxCycles instructions and returns the
+// distinct mnemonics it executed with their counts.
+func CoverageReport(path string, maxCycles int) (map[string]uint64, error) {
+	c := Chip8{}
+	c.Init()
+	if err := c.LoadROM(path); err != nil {
+		return nil, err
+	}
+	for i := 0; i < maxCycles; i++ {
+		if err := c.Cycle(); err != nil {
+			return c.OpcodeCounts(), err
+		}
+	}
+	return c.OpcodeCounts(), nil
+}
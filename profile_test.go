@@ -0,0 +1,25 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCoverageReportIBM(t *testing.T) {
+	counts, err := CoverageReport("assets/roms/ibm.ch8", 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for m := range counts {
+		got = append(got, m)
+	}
+	slices.Sort(got)
+	want := []string{"00E0", "1NNN", "6XNN", "7XNN", "ANNN", "DXYN"}
+	if !slices.Equal(got, want) {
+		t.Errorf("IBM logo coverage = %v, want %v", got, want)
+	}
+	if counts["00E0"] != 1 {
+		t.Errorf("00E0 ran %d times, want 1", counts["00E0"])
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+)
+
+type Variant int
+
+const (
+	VariantChip8 Variant = iota
+	VariantSCHIP
+	VariantXOChip
+)
+
+type Quirks struct {
+	ShiftUsesVY   bool // 8XY6/8XYE shift VY into VX instead of shifting VX
+	ShiftSetsVF   bool // 8XY6/8XYE write the shifted-out bit to VF, as nearly all interpreters do
+	LogicResetsVF bool // 8XY1/8XY2/8XY3 reset VF to 0
+	IncrementI    bool // FX55/FX65 leave I at I+X+1
+	JumpUsesVX    bool // BXNN jumps to XNN+VX instead of NNN+V0
+	WrapX         bool // sprites wrap around the right edge instead of clipping
+	WrapY         bool // sprites wrap around the bottom edge instead of clipping
+
+	WaitKeyOnRelease bool // FX0A completes when the key is released, as on the VIP
+	DisplayWait      bool // at most one DXYN per frame, see RunFrame
+	SpriteReadWraps  bool // DXYN reading past 0xFFF wraps to 0x000 instead of truncating
+	IWrapsAtMemEnd   bool // I and every access through it wrap at 0xFFF instead of faulting
+}
+
+// SetQuirks selects the quirks to run with, including Quirks{} for every
+// quirk off, which Init would otherwise replace with DefaultQuirks. Quirks
+// set this way, or settled by the first Init, are kept across Reset.
+func (c *Chip8) SetQuirks(q Quirks) {
+	c.Quirks = q
+	c.quirksSet = true
+}
+
+// SetWrapSprites sets wrapping on both axes.
+func (q *Quirks) SetWrapSprites(wrap bool) {
+	q.WrapX = wrap
+	q.WrapY = wrap
+}
+
+var (
+	DefaultQuirks = Quirks{ShiftSetsVF: true, WrapX: true, WrapY: true, WaitKeyOnRelease: true}
+	CosmacQuirks  = Quirks{ShiftUsesVY: true, ShiftSetsVF: true, LogicResetsVF: true, IncrementI: true, WaitKeyOnRelease: true, DisplayWait: true}
+	SchipQuirks   = Quirks{ShiftSetsVF: true, JumpUsesVX: true}
+	XOChipQuirks  = Quirks{ShiftUsesVY: true, ShiftSetsVF: true, IncrementI: true, WrapX: true, WrapY: true, WaitKeyOnRelease: true}
+)
+
+var platformQuirks = map[string]Quirks{
+	"originalChip8": CosmacQuirks,
+	"superchip":     SchipQuirks,
+	"xochip":        XOChipQuirks,
+}
+
+var platformVariants = map[string]Variant{
+	"originalChip8": VariantChip8,
+	"superchip":     VariantSCHIP,
+	"xochip":        VariantXOChip,
+}
+
+type romEntry struct {
+	Name     string `json:"name"`
+	Platform string `json:"platform"`
+}
+
+//go:embed assets/chip8db.json
+var romDatabaseJSON []byte
+
+var romDatabase map[string]romEntry
+
+func lookupROM(data []byte) (romEntry, bool) {
+	if romDatabase == nil {
+		if err := json.Unmarshal(romDatabaseJSON, &romDatabase); err != nil {
+			return romEntry{}, false
+		}
+	}
+	sum := sha1.Sum(data)
+	entry, ok := romDatabase[hex.EncodeToString(sum[:])]
+	return entry, ok
+}
+
+// DetectVariant looks the ROM up by SHA-1 in the embedded CHIP-8 database and
+// returns the quirks for its platform, or DefaultQuirks when it is unknown.
+func DetectVariant(data []byte) (Quirks, bool) {
+	entry, ok := lookupROM(data)
+	if !ok {
+		return DefaultQuirks, false
+	}
+	q, ok := platformQuirks[entry.Platform]
+	if !ok {
+		return DefaultQuirks, false
+	}
+	return q, true
+}
+
+// DetectQuirks applies the quirks and variant detected for the loaded ROM, if
+// known.
+func (c *Chip8) DetectQuirks() bool {
+	data := c.memory[0x200 : 0x200+c.romLen]
+	q, ok := DetectVariant(data)
+	if ok {
+		c.Quirks = q
+		entry, _ := lookupROM(data)
+		c.Variant = platformVariants[entry.Platform]
+	}
+	return ok
+}
+
+func (v Variant) String() string {
+	switch v {
+	case VariantSCHIP:
+		return "SCHIP"
+	case VariantXOChip:
+		return "XO-CHIP"
+	}
+	return "CHIP-8"
+}
+
+// ActiveProfile returns the quirks currently in effect.
+func (c *Chip8) ActiveProfile() Quirks {
+	return c.Quirks
+}
+
+// IsExtended reports whether the SCHIP/XO-CHIP opcodes, such as 00FF, are
+// enabled.
+func (c *Chip8) IsExtended() bool {
+	return c.Variant != VariantChip8
+}
+
+func (c *Chip8) MemoryBytes() int {
+	return len(c.memory)
+}
+
+// PlaneCount is the number of bit planes the variant can draw to.
+func (c *Chip8) PlaneCount() int {
+	if c.Variant == VariantXOChip {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,121 @@
+package main
+
+import "testing"
+
+func TestDetectVariantKnownROM(t *testing.T) {
+	data, err := EmbeddedROM("ibm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, ok := DetectVariant(data)
+	if !ok {
+		t.Fatal("IBM logo not found in the ROM database")
+	}
+	if q != CosmacQuirks {
+		t.Errorf("quirks = %+v, want CosmacQuirks", q)
+	}
+}
+
+func TestDetectVariantUnknownROM(t *testing.T) {
+	q, ok := DetectVariant([]byte{0x12, 0x00})
+	if ok || q != DefaultQuirks {
+		t.Errorf("DetectVariant = %+v, %v, want DefaultQuirks, false", q, ok)
+	}
+}
+
+func TestInitDefaultsZeroQuirks(t *testing.T) {
+	c := &Chip8{}
+	c.Init()
+	if c.Quirks != DefaultQuirks {
+		t.Errorf("Quirks = %+v, want DefaultQuirks", c.Quirks)
+	}
+}
+
+func TestSetQuirksAllOffSurvivesReset(t *testing.T) {
+	c := &Chip8{}
+	c.SetQuirks(Quirks{})
+	c.Init()
+	if c.Quirks != (Quirks{}) {
+		t.Fatalf("after Init Quirks = %+v, want all off", c.Quirks)
+	}
+	c.Reset()
+	if c.Quirks != (Quirks{}) {
+		t.Errorf("after Reset Quirks = %+v, want all off", c.Quirks)
+	}
+}
+
+func TestZeroQuirksAfterInitSurviveReset(t *testing.T) {
+	c := &Chip8{}
+	c.Init()
+	c.Quirks = Quirks{}
+	c.Reset()
+	if c.Quirks != (Quirks{}) {
+		t.Errorf("after Reset Quirks = %+v, want all off", c.Quirks)
+	}
+}
+
+func TestShiftSetsVF(t *testing.T) {
+	for _, tc := range []struct {
+		opcode uint16
+		sets   bool
+		wantVF byte
+	}{
+		{0x8016, true, 1},  // 0x03 >> 1 shifts out a 1
+		{0x8016, false, 7}, // VF untouched
+		{0x801E, true, 0},  // 0x03 << 1 shifts out a 0
+		{0x801E, false, 7},
+	} {
+		c := &Chip8{}
+		q := DefaultQuirks
+		q.ShiftSetsVF = tc.sets
+		c.SetQuirks(q)
+		c.Init()
+		c.V[0] = 0x03
+		c.V[0xF] = 7
+		if err := c.Execute(tc.opcode); err != nil {
+			t.Fatal(err)
+		}
+		if c.V[0xF] != tc.wantVF {
+			t.Errorf("%04X with ShiftSetsVF=%v: VF = %d, want %d", tc.opcode, tc.sets, c.V[0xF], tc.wantVF)
+		}
+	}
+}
+
+func TestDefaultProfilesSetVFOnShift(t *testing.T) {
+	for name, q := range map[string]Quirks{
+		"default": DefaultQuirks, "cosmac": CosmacQuirks, "schip": SchipQuirks, "xochip": XOChipQuirks,
+	} {
+		if !q.ShiftSetsVF {
+			t.Errorf("%s profile has ShiftSetsVF off", name)
+		}
+	}
+}
+
+func TestSetWrapSpritesSetsBothAxes(t *testing.T) {
+	var q Quirks
+	q.SetWrapSprites(true)
+	if !q.WrapX || !q.WrapY {
+		t.Errorf("SetWrapSprites(true) = %+v", q)
+	}
+	q.SetWrapSprites(false)
+	if q.WrapX || q.WrapY {
+		t.Errorf("SetWrapSprites(false) = %+v", q)
+	}
+}
+
+func TestActiveProfileSchip(t *testing.T) {
+	c := &Chip8{Variant: VariantSCHIP}
+	c.SetQuirks(SchipQuirks)
+	c.Init()
+	if got := c.ActiveProfile(); got != SchipQuirks {
+		t.Errorf("ActiveProfile = %+v, want %+v", got, SchipQuirks)
+	}
+	if !c.IsExtended() || c.MemoryBytes() != 4096 || c.PlaneCount() != 1 || c.Variant.String() != "SCHIP" {
+		t.Errorf("extended=%v memory=%d planes=%d variant=%s, want true, 4096, 1 and SCHIP",
+			c.IsExtended(), c.MemoryBytes(), c.PlaneCount(), c.Variant)
+	}
+	c.Variant = VariantXOChip
+	if c.PlaneCount() != 2 {
+		t.Errorf("XO-CHIP PlaneCount = %d, want 2", c.PlaneCount())
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import "fmt"
+
+// quirksTestFrames bounds a RunQuirksTest run; the ROM halts in well under
+// a second even with DisplayWait.
+const quirksTestFrames = 120
+
+// quirksTestChecks lists, in the order the quirks ROM draws its results, the
+// name of each check and whether the given quirks should show it.
+var quirksTestChecks = []struct {
+	name string
+	on   func(Quirks) bool
+}{
+	{"vfreset", func(q Quirks) bool { return q.LogicResetsVF }},
+	{"shifting", func(q Quirks) bool { return q.ShiftUsesVY }},
+	{"memory", func(q Quirks) bool { return q.IncrementI }},
+	{"jumping", func(q Quirks) bool { return q.JumpUsesVX }},
+	{"wrapx", func(q Quirks) bool { return q.WrapX }},
+	{"wrapy", func(q Quirks) bool { return q.WrapY }},
+	{"dispwait", func(q Quirks) bool { return q.DisplayWait }},
+}
+
+// RunQuirksTest runs the embedded quirks ROM under quirks and reports, for
+// each check it makes, whether the behaviour it saw matches the quirks asked
+// for. A false result means the emulator does not honour that setting.
+func RunQuirksTest(quirks Quirks) (results map[string]bool, err error) {
+	seen, err := observeQuirks(quirks)
+	if err != nil {
+		return nil, err
+	}
+	results = make(map[string]bool, len(quirksTestChecks))
+	for _, check := range quirksTestChecks {
+		results[check.name] = seen[check.name] == check.on(quirks)
+	}
+	return results, nil
+}
+
+// observeQuirks runs the quirks ROM and reads back, from the digits it
+// draws, which quirk behaviours it saw.
+func observeQuirks(quirks Quirks) (map[string]bool, error) {
+	c := &Chip8{}
+	c.SetQuirks(quirks)
+	c.Init()
+	if err := c.LoadEmbeddedROM("quirks"); err != nil {
+		return nil, err
+	}
+	for frame := 0; !c.halted(); frame++ {
+		if frame == quirksTestFrames {
+			return nil, fmt.Errorf("quirks test did not finish in %d frames", quirksTestFrames)
+		}
+		if err := c.RunFrame(); err != nil {
+			return nil, err
+		}
+		c.TickFrame()
+	}
+	seen := make(map[string]bool, len(quirksTestChecks))
+	for i, check := range quirksTestChecks {
+		switch c.digitAt(i*5, 0) {
+		case 0:
+			seen[check.name] = false
+		case 1:
+			seen[check.name] = true
+		default:
+			return nil, fmt.Errorf("quirks test: unreadable result for %s", check.name)
+		}
+	}
+	return seen, nil
+}
+
+// digitAt returns which small font digit is drawn with its top left corner
+// at x, y, or -1 if the pixels there are not one.
+func (c *Chip8) digitAt(x, y int) int {
+	for digit := 0; digit < 16; digit++ {
+		match := true
+		for row := 0; row < 5 && match; row++ {
+			for col := 0; col < 4; col++ {
+				lit := fontset[digit*5+row]&(0x80>>col) != 0
+				if lit != (c.display[x+col][y+row]&1 != 0) {
+					match = false
+					break
+				}
+			}
+		}
+		if match {
+			return digit
+		}
+	}
+	return -1
+}
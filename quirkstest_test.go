@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestObserveQuirksProfiles(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		quirks Quirks
+		want   map[string]bool
+	}{
+		{"cosmac", CosmacQuirks, map[string]bool{
+			"vfreset": true, "shifting": true, "memory": true, "jumping": false,
+			"wrapx": false, "wrapy": false, "dispwait": true,
+		}},
+		{"schip", SchipQuirks, map[string]bool{
+			"vfreset": false, "shifting": false, "memory": false, "jumping": true,
+			"wrapx": false, "wrapy": false, "dispwait": false,
+		}},
+	} {
+		seen, err := observeQuirks(tc.quirks)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		for check, want := range tc.want {
+			if seen[check] != want {
+				t.Errorf("%s: %s seen = %v, want %v", tc.name, check, seen[check], want)
+			}
+		}
+	}
+}
+
+func TestRunQuirksTestPasses(t *testing.T) {
+	for name, q := range map[string]Quirks{"cosmac": CosmacQuirks, "schip": SchipQuirks, "default": DefaultQuirks} {
+		results, err := RunQuirksTest(q)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(results) != len(quirksTestChecks) {
+			t.Errorf("%s: %d results, want %d", name, len(results), len(quirksTestChecks))
+		}
+		for check, pass := range results {
+			if !pass {
+				t.Errorf("%s: %s failed", name, check)
+			}
+		}
+	}
+}
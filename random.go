@@ -0,0 +1,26 @@
+package main
+
+import (
+	"io"
+	"math/rand"
+)
+
+// SetSeed makes CXNN reproducible by seeding a private PRNG.
+func (c *Chip8) SetSeed(seed int64) {
+	c.rng = rand.New(rand.NewSource(seed))
+}
+
+// randomByte reads from RandSource when set, falling back to the PRNG when it
+// is nil or the read fails.
+func (c *Chip8) randomByte() byte {
+	if c.RandSource != nil {
+		var b [1]byte
+		if _, err := io.ReadFull(c.RandSource, b[:]); err == nil {
+			return b[0]
+		}
+	}
+	if c.rng != nil {
+		return byte(c.rng.Intn(256))
+	}
+	return byte(rand.Intn(256))
+}
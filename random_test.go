@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRandSourceScriptsCXNN(t *testing.T) {
+	c := newTestChip(t, 0xC00F, 0xC1F0, 0xC2FF)
+	c.RandSource = bytes.NewReader([]byte{0xAB, 0xCD, 0x5A})
+	step(t, c, 3)
+	if c.V[0] != 0x0B || c.V[1] != 0xC0 || c.V[2] != 0x5A {
+		t.Errorf("V0..V2 = %02X %02X %02X, want 0B C0 5A", c.V[0], c.V[1], c.V[2])
+	}
+}
+
+func TestRandSourceFallsBackWhenExhausted(t *testing.T) {
+	c := newTestChip(t, 0xC0FF, 0xC1FF)
+	c.SetSeed(1)
+	want := newTestChip(t, 0xC1FF)
+	want.SetSeed(1)
+	step(t, want, 1)
+	c.RandSource = bytes.NewReader([]byte{0x42})
+	step(t, c, 2)
+	if c.V[0] != 0x42 || c.V[1] != want.V[1] {
+		t.Errorf("V0, V1 = %02X %02X, want 42 %02X from the seeded PRNG", c.V[0], c.V[1], want.V[1])
+	}
+}
+
+func TestSetSeedReproducible(t *testing.T) {
+	a, b := newTestChip(t, 0xC0FF, 0xC1FF), newTestChip(t, 0xC0FF, 0xC1FF)
+	a.SetSeed(7)
+	b.SetSeed(7)
+	step(t, a, 2)
+	step(t, b, 2)
+	if a.V != b.V {
+		t.Errorf("same seed gave %v and %v", a.V[:2], b.V[:2])
+	}
+}
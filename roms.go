@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/sha1"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//go:embed assets/roms/*.ch8
+var embeddedROMs embed.FS
+
+// ListEmbeddedROMs returns the names of the ROMs bundled with the binary.
+func ListEmbeddedROMs() []string {
+	entries, _ := embeddedROMs.ReadDir("assets/roms")
+	var names []string
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".ch8"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EmbeddedROM returns the bytes of a bundled ROM such as "ibm".
+func EmbeddedROM(name string) ([]byte, error) {
+	data, err := embeddedROMs.ReadFile("assets/roms/" + name + ".ch8")
+	if err != nil {
+		return nil, fmt.Errorf("unknown embedded ROM: %s", name)
+	}
+	return data, nil
+}
+
+func (c *Chip8) LoadEmbeddedROM(name string) error {
+	data, err := EmbeddedROM(name)
+	if err != nil {
+		return err
+	}
+	if err := c.loadROMData(data); err != nil {
+		return err
+	}
+	c.romPaths = nil
+	return nil
+}
+
+// ROMInfo identifies the loaded ROM for bug reports: its SHA-1, its name in
+// the embedded ROM database or "" when unknown, and its size in bytes.
+func (c *Chip8) ROMInfo() (sum string, name string, size int) {
+	h := sha1.Sum(c.rom)
+	entry, _ := lookupROM(c.rom)
+	return hex.EncodeToString(h[:]), entry.Name, len(c.rom)
+}
+
+// romPaddingMin is the shortest run of 0x00 or 0xFF at the end of a ROM that
+// CodeLength treats as padding rather than data.
+const romPaddingMin = 16
+
+// CodeLength is the loaded ROM's length without trailing padding: a run of
+// at least romPaddingMin 0x00 or 0xFF bytes, as left by tools that round
+// ROMs up to a fixed size. It is rounded up to whole instructions, so it is
+// the ROM length when there is no such run.
+func (c *Chip8) CodeLength() int {
+	n := len(c.rom)
+	if n == 0 || (c.rom[n-1] != 0x00 && c.rom[n-1] != 0xFF) {
+		return n
+	}
+	pad := c.rom[n-1]
+	end := n
+	for end > 0 && c.rom[end-1] == pad {
+		end--
+	}
+	if n-end < romPaddingMin {
+		return n
+	}
+	return (end + 1) &^ 1
+}
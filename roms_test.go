@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"testing"
+)
+
+func TestEmbeddedROMsRun(t *testing.T) {
+	names := ListEmbeddedROMs()
+	for _, want := range []string{"ibm", "keypad", "quirks"} {
+		if _, err := EmbeddedROM(want); err != nil {
+			t.Errorf("%s not embedded: %v", want, err)
+		}
+	}
+	for _, name := range names {
+		c := &Chip8{Strict: true}
+		c.Init()
+		if err := c.LoadEmbeddedROM(name); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for i := 0; i < 200; i++ {
+			if err := c.Cycle(); err != nil {
+				t.Fatalf("%s: cycle %d: %v", name, i, err)
+			}
+		}
+	}
+}
+
+func TestEmbeddedROMUnknown(t *testing.T) {
+	if _, err := EmbeddedROM("nope"); err == nil {
+		t.Error("EmbeddedROM(nope) succeeded")
+	}
+}
+
+func TestROMInfoIBM(t *testing.T) {
+	c := &Chip8{}
+	c.Init()
+	if err := c.LoadEmbeddedROM("ibm"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := EmbeddedROM("ibm")
+	sum, name, size := c.ROMInfo()
+	if want := fmt.Sprintf("%x", sha1.Sum(data)); sum != want {
+		t.Errorf("sum = %s, want %s", sum, want)
+	}
+	if name != "IBM Logo" || size != len(data) {
+		t.Errorf("ROMInfo name %q size %d, want IBM Logo and %d", name, size, len(data))
+	}
+}
+
+func TestROMInfoUnknown(t *testing.T) {
+	c := newTestChip(t, 0x1200)
+	if _, name, size := c.ROMInfo(); name != "" || size != 2 {
+		t.Errorf("ROMInfo name %q size %d, want \"\" and 2", name, size)
+	}
+}
+
+func TestCodeLength(t *testing.T) {
+	code := []byte{0x60, 0x01, 0x12, 0x02}
+	for _, tc := range []struct {
+		name string
+		rom  []byte
+		want int
+	}{
+		{"no padding", code, 4},
+		{"zero padding", append(append([]byte(nil), code...), make([]byte, 60)...), 4},
+		{"FF padding", append(append([]byte(nil), code...), bytes.Repeat([]byte{0xFF}, 16)...), 4},
+		{"short zero run kept", append(append([]byte(nil), code...), make([]byte, 15)...), 19},
+		{"odd code end rounded up", append([]byte{0x60, 0x01, 0x12}, make([]byte, 20)...), 4},
+	} {
+		c := &Chip8{}
+		c.Init()
+		if err := c.loadROMData(tc.rom); err != nil {
+			t.Fatal(err)
+		}
+		if got := c.CodeLength(); got != tc.want {
+			t.Errorf("%s: CodeLength = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
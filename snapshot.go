@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"image"
+)
+
+type snapshot struct {
+	Memory  [4096]byte
+	Display [128][64]byte
+	PC      uint16
+	I       uint16
+	Stack   []uint16
+	SP      byte
+	V       [16]byte
+	DT      byte
+	ST      byte
+	Hires   bool
+	Planes  byte
+}
+
+func (c *Chip8) snapshot() snapshot {
+	return snapshot{
+		Memory:  c.memory,
+		Display: c.display,
+		PC:      c.PC,
+		I:       c.I,
+		Stack:   append([]uint16(nil), c.stack...),
+		SP:      c.SP,
+		V:       c.V,
+		DT:      c.DelayTimer(),
+		ST:      c.SoundTimer(),
+		Hires:   c.hires,
+		Planes:  c.planes,
+	}
+}
+
+// snapshotMagic and snapshotVersion open every snapshot. The layout after
+// them, with all multi-byte fields big-endian so saves move between hosts:
+//
+//	PC       uint16
+//	I        uint16
+//	SP       uint8
+//	depth    uint8, then depth uint16 stack entries
+//	V        16 bytes
+//	DT, ST   uint8 each
+//	Hires    uint8, 0 or 1
+//	Planes   uint8
+//	Memory   4096 bytes
+//	Display  128*64 bytes, column by column
+//
+// Snapshots without the magic are read as the older gob encoding.
+const (
+	snapshotMagic   = "C8SS"
+	snapshotVersion = 1
+)
+
+// Snapshot encodes the machine state so it can be restored later.
+func (c *Chip8) Snapshot() ([]byte, error) {
+	return c.snapshot().encode()
+}
+
+func (s snapshot) encode() ([]byte, error) {
+	if len(s.Stack) > 255 {
+		return nil, fmt.Errorf("stack of %d too deep for a snapshot", len(s.Stack))
+	}
+	buf := append([]byte(snapshotMagic), snapshotVersion)
+	buf = binary.BigEndian.AppendUint16(buf, s.PC)
+	buf = binary.BigEndian.AppendUint16(buf, s.I)
+	buf = append(buf, s.SP, byte(len(s.Stack)))
+	for _, addr := range s.Stack {
+		buf = binary.BigEndian.AppendUint16(buf, addr)
+	}
+	buf = append(buf, s.V[:]...)
+	buf = append(buf, s.DT, s.ST)
+	if s.Hires {
+		buf = append(buf, 1)
+	} else {
+		buf = append(buf, 0)
+	}
+	buf = append(buf, s.Planes)
+	buf = append(buf, s.Memory[:]...)
+	for x := range s.Display {
+		buf = append(buf, s.Display[x][:]...)
+	}
+	return buf, nil
+}
+
+func decodeSnapshot(data []byte) (snapshot, error) {
+	var s snapshot
+	if !bytes.HasPrefix(data, []byte(snapshotMagic)) {
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&s); err != nil {
+			return s, fmt.Errorf("bad snapshot: %w", err)
+		}
+		return s, nil
+	}
+	r := bytes.NewReader(data[len(snapshotMagic):])
+	version, err := r.ReadByte()
+	if err != nil {
+		return s, fmt.Errorf("bad snapshot: %w", err)
+	}
+	if version != snapshotVersion {
+		return s, fmt.Errorf("bad snapshot: unsupported version %d", version)
+	}
+	var head struct {
+		PC, I     uint16
+		SP, Depth byte
+	}
+	if err := binary.Read(r, binary.BigEndian, &head); err != nil {
+		return s, fmt.Errorf("bad snapshot: %w", err)
+	}
+	s.PC, s.I, s.SP = head.PC, head.I, head.SP
+	s.Stack = make([]uint16, head.Depth)
+	var tail struct {
+		V             [16]byte
+		DT, ST, Hires byte
+		Planes        byte
+		Memory        [4096]byte
+		Display       [128][64]byte
+	}
+	for _, v := range []any{s.Stack, &tail} {
+		if err := binary.Read(r, binary.BigEndian, v); err != nil {
+			return s, fmt.Errorf("bad snapshot: %w", err)
+		}
+	}
+	if r.Len() != 0 {
+		return s, fmt.Errorf("bad snapshot: %d trailing bytes", r.Len())
+	}
+	s.V, s.DT, s.ST = tail.V, tail.DT, tail.ST
+	s.Hires, s.Planes = tail.Hires != 0, tail.Planes
+	s.Memory, s.Display = tail.Memory, tail.Display
+	return s, nil
+}
+
+// Restore loads a state produced by Snapshot.
+func (c *Chip8) Restore(data []byte) error {
+	s, err := decodeSnapshot(data)
+	if err != nil {
+		return err
+	}
+	if int(s.SP) > len(s.Stack) {
+		return fmt.Errorf("bad snapshot: SP %d beyond stack of %d", s.SP, len(s.Stack))
+	}
+	c.memory = s.Memory
+	c.display = s.Display
+	c.markAllDirty()
+	c.PC = s.PC
+	c.I = s.I
+	c.stack = s.Stack
+	c.StackSize = len(s.Stack)
+	c.SP = s.SP
+	c.V = s.V
+	c.SetDelayTimer(s.DT)
+	c.SetSoundTimer(s.ST)
+	c.hires = s.Hires
+	c.planes = s.Planes
+	return nil
+}
+
+type SnapshotDiff struct {
+	Registers []string      // names as accepted by GetRegister, plus "stack"
+	Memory    []uint16      // differing addresses
+	Pixels    []image.Point // differing display pixels
+}
+
+func (d SnapshotDiff) Empty() bool {
+	return len(d.Registers) == 0 && len(d.Memory) == 0 && len(d.Pixels) == 0
+}
+
+// DiffSnapshots reports which registers, memory addresses and pixels differ
+// between two snapshots.
+func DiffSnapshots(a, b []byte) (SnapshotDiff, error) {
+	var d SnapshotDiff
+	sa, err := decodeSnapshot(a)
+	if err != nil {
+		return d, err
+	}
+	sb, err := decodeSnapshot(b)
+	if err != nil {
+		return d, err
+	}
+	for i := range sa.V {
+		if sa.V[i] != sb.V[i] {
+			d.Registers = append(d.Registers, fmt.Sprintf("V%X", i))
+		}
+	}
+	regs := []struct {
+		name string
+		a, b uint16
+	}{
+		{"I", sa.I, sb.I},
+		{"PC", sa.PC, sb.PC},
+		{"SP", uint16(sa.SP), uint16(sb.SP)},
+		{"DT", uint16(sa.DT), uint16(sb.DT)},
+		{"ST", uint16(sa.ST), uint16(sb.ST)},
+	}
+	for _, r := range regs {
+		if r.a != r.b {
+			d.Registers = append(d.Registers, r.name)
+		}
+	}
+	if fmt.Sprint(sa.Stack) != fmt.Sprint(sb.Stack) {
+		d.Registers = append(d.Registers, "stack")
+	}
+	for addr := range sa.Memory {
+		if sa.Memory[addr] != sb.Memory[addr] {
+			d.Memory = append(d.Memory, uint16(addr))
+		}
+	}
+	for x := range sa.Display {
+		for y := range sa.Display[x] {
+			if sa.Display[x][y] != sb.Display[x][y] {
+				d.Pixels = append(d.Pixels, image.Pt(x, y))
+			}
+		}
+	}
+	return d, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"slices"
+	"testing"
+)
+
+func mustSnapshot(t *testing.T, c *Chip8) []byte {
+	t.Helper()
+	data, err := c.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDiffSnapshotsAfter6XNN(t *testing.T) {
+	c := newTestChip(t, 0x6342)
+	before := mustSnapshot(t, c)
+	step(t, c, 1)
+	d, err := DiffSnapshots(before, mustSnapshot(t, c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(d.Registers, []string{"V3", "PC"}) || len(d.Memory) != 0 || len(d.Pixels) != 0 {
+		t.Errorf("diff = %+v, want only V3 and PC", d)
+	}
+}
+
+func TestDiffSnapshotsIdentical(t *testing.T) {
+	c := newTestChip(t, 0x6342)
+	data := mustSnapshot(t, c)
+	d, err := DiffSnapshots(data, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Empty() {
+		t.Errorf("diff of a snapshot with itself = %+v", d)
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	c := newTestChip(t, 0x6A42, 0xA321, 0x2208, 0x1206, 0xD015, 0xF318)
+	c.Variant = VariantSCHIP
+	step(t, c, 5)
+	c.SetDelayTimer(9)
+	c.hires = true
+	data := mustSnapshot(t, c)
+
+	r := newTestChip(t)
+	if err := r.Restore(data); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(mustSnapshot(t, r), data) {
+		t.Fatal("restored machine snapshots differently")
+	}
+	if r.PC != c.PC || r.I != 0x321 || r.SP != 1 || r.V != c.V || r.DelayTimer() != 9 ||
+		r.SoundTimer() != c.SoundTimer() || !r.hires || r.display != c.display || r.memory != c.memory {
+		t.Errorf("restored state differs: PC=%03X I=%03X SP=%d", r.PC, r.I, r.SP)
+	}
+}
+
+func TestSnapshotHeaderBigEndian(t *testing.T) {
+	c := newTestChip(t)
+	c.PC, c.I = 0x234, 0xABC
+	c.SP = 1
+	c.stack[0] = 0x2A4
+	c.V[0], c.V[0xF] = 0x11, 0xFF
+	data := mustSnapshot(t, c)
+	want := []byte{'C', '8', 'S', 'S', 1, 0x02, 0x34, 0x0A, 0xBC, 1, 16, 0x02, 0xA4}
+	if !bytes.HasPrefix(data, want) {
+		t.Errorf("header = % X, want % X", data[:len(want)], want)
+	}
+	if n := len(want) + 15*2 + 16 + 4 + 4096 + 128*64; len(data) != n {
+		t.Errorf("snapshot is %d bytes, want %d", len(data), n)
+	}
+}
+
+func TestRestoreGobSnapshot(t *testing.T) {
+	c := newTestChip(t, 0x6A42)
+	step(t, c, 1)
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(c.snapshot()); err != nil {
+		t.Fatal(err)
+	}
+	r := newTestChip(t)
+	if err := r.Restore(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if r.V[0xA] != 0x42 || r.PC != 0x202 {
+		t.Errorf("gob restore: VA=%02X PC=%03X", r.V[0xA], r.PC)
+	}
+}
+
+func TestRestoreRejectsBadSnapshots(t *testing.T) {
+	c := newTestChip(t)
+	good := mustSnapshot(t, c)
+	bad := append([]byte(nil), good...)
+	bad[4] = 99
+	for name, data := range map[string][]byte{
+		"version":   bad,
+		"truncated": good[:100],
+		"trailing":  append(append([]byte(nil), good...), 0),
+		"garbage":   []byte("hello"),
+	} {
+		if err := c.Restore(data); err == nil {
+			t.Errorf("%s snapshot restored", name)
+		}
+	}
+}
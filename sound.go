@@ -1,6 +1,11 @@
 package main
 
 import (
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/faiface/beep"
@@ -10,26 +15,69 @@ import (
 var (
 	sampleRate = beep.SampleRate(44100)
 	beepSound  beep.Streamer
+
+	speakerInit    = speaker.Init // replaced in tests to simulate missing audio
+	audioAvailable bool
+	audioWarning   sync.Once
+
+	beepFrequency atomic.Uint64 // math.Float64bits of the tone in Hz
 )
 
+const baseBeepFrequency = 440.0
+
+// pitchFrequency maps the XO-CHIP pitch register to a tone, 64 being the base
+// frequency: base * 2^((pitch-64)/48).
+func pitchFrequency(pitch byte) float64 {
+	return baseBeepFrequency * math.Pow(2, (float64(pitch)-64)/48)
+}
+
+// SetBeepFrequency changes the tone of the beep, including one already playing.
+func SetBeepFrequency(freq float64) {
+	beepFrequency.Store(math.Float64bits(freq))
+}
+
+func currentBeepFrequency() float64 {
+	if bits := beepFrequency.Load(); bits != 0 {
+		return math.Float64frombits(bits)
+	}
+	return baseBeepFrequency
+}
+
+// InitSound opens the audio device. If that fails the emulator keeps running
+// silently and PlayBeep/StopBeep become no-ops.
 func InitSound() {
-	speaker.Init(sampleRate, sampleRate.N(time.Second/10))
-	beepSound = squareWave(440)
+	if err := speakerInit(sampleRate, sampleRate.N(time.Second/10)); err != nil {
+		audioAvailable = false
+		audioWarning.Do(func() {
+			fmt.Fprintln(os.Stderr, "audio unavailable, running silently:", err)
+		})
+		return
+	}
+	beepSound = squareWave()
+	audioAvailable = true
 }
 
 func PlayBeep() {
+	if !audioAvailable {
+		return
+	}
 	speaker.Play(beepSound)
 }
 
 func StopBeep() {
+	if !audioAvailable {
+		return
+	}
 	speaker.Clear()
 }
 
-func squareWave(freq float64) beep.Streamer {
+func squareWave() beep.Streamer {
+	phase := 0.0
 	return beep.StreamerFunc(func(samples [][2]float64) (n int, ok bool) {
+		step := currentBeepFrequency() / float64(sampleRate)
 		for i := range samples {
-			t := float64(i) / float64(sampleRate)
-			if int(t*freq*2)%2 == 0 {
+			phase = math.Mod(phase+step, 1)
+			if phase < 0.5 {
 				samples[i][0] = 0.5
 				samples[i][1] = 0.5
 			} else {
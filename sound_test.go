@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/faiface/beep"
+)
+
+func TestInitSoundWithoutDevice(t *testing.T) {
+	saved := speakerInit
+	defer func() { speakerInit = saved }()
+	speakerInit = func(beep.SampleRate, int) error { return errors.New("no audio device") }
+
+	InitSound()
+	if audioAvailable {
+		t.Fatal("audio marked available after a failed init")
+	}
+	PlayBeep()
+	StopBeep()
+}
+
+func TestPitchFrequency(t *testing.T) {
+	for _, tc := range []struct {
+		pitch byte
+		want  float64
+	}{
+		{64, 440},
+		{112, 880},
+		{16, 220},
+		{100, 440 * math.Pow(2, 36.0/48)},
+	} {
+		if got := pitchFrequency(tc.pitch); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("pitchFrequency(%d) = %v, want %v", tc.pitch, got, tc.want)
+		}
+	}
+}
+
+func TestFX3ASetsBeepFrequency(t *testing.T) {
+	defer SetBeepFrequency(baseBeepFrequency)
+	c := newTestChip(t, 0x6070, 0xF03A)
+	c.Variant = VariantXOChip
+	if f := currentBeepFrequency(); f != baseBeepFrequency {
+		t.Fatalf("default frequency = %v, want %v", f, baseBeepFrequency)
+	}
+	step(t, c, 2)
+	if got, want := currentBeepFrequency(), pitchFrequency(0x70); got != want {
+		t.Errorf("frequency after FX3A = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import "time"
+
+type statsSample struct {
+	at     time.Time
+	cycles uint64
+	frames uint64
+	ips    float64
+	fps    float64
+}
+
+func (c *Chip8) now() time.Time {
+	if c.clock != nil {
+		return c.clock()
+	}
+	return time.Now()
+}
+
+// Stats returns the effective instructions and frames per second, measured
+// from the Cycles and frame counters and refreshed at most once per second.
+func (c *Chip8) Stats() (ips, fps float64) {
+	now := c.now()
+	frames := c.frames.Load()
+	s := &c.stats
+	if s.at.IsZero() {
+		s.at, s.cycles, s.frames = now, c.Cycles, frames
+		return 0, 0
+	}
+	if elapsed := now.Sub(s.at).Seconds(); elapsed >= 1 {
+		s.ips = float64(c.Cycles-s.cycles) / elapsed
+		s.fps = float64(frames-s.frames) / elapsed
+		s.at, s.cycles, s.frames = now, c.Cycles, frames
+	}
+	return s.ips, s.fps
+}
+
+type DrawStats struct {
+	Sprites       uint64 // DXYN instructions executed
+	PixelsToggled uint64
+	Collisions    uint64 // pixels turned off by a draw
+	LastX         int
+	LastY         int
+	LastHeight    int
+}
+
+// DrawStats returns the sprite counters collected since the last Reset.
+func (c *Chip8) DrawStats() DrawStats {
+	return c.drawStats
+}
+
+// RunUnthrottled executes up to cycles instructions back to back, with no
+// sleeping or rendering, stopping after the first instruction that fails;
+// Elapsed is the wall time taken. The timers are ticked once every
+// InstructionsPerFrame instructions so timer loops still finish.
+func (c *Chip8) RunUnthrottled(cycles int) RunResult {
+	perFrame := c.InstructionsPerFrame
+	if perFrame <= 0 {
+		perFrame = 8
+	}
+	start := c.now()
+	var r RunResult
+	for r.Instructions < cycles {
+		r.Instructions++
+		if r.Err = c.Cycle(); r.Err != nil {
+			break
+		}
+		if r.Instructions%perFrame == 0 {
+			c.tickTimers()
+		}
+	}
+	return c.finishRun(r, start)
+}
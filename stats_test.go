@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsOverKnownInterval(t *testing.T) {
+	c := newTestChip(t, 0x1200)
+	clock := withFakeClock(c)
+	if ips, fps := c.Stats(); ips != 0 || fps != 0 {
+		t.Fatalf("baseline Stats = %v, %v, want 0, 0", ips, fps)
+	}
+	step(t, c, 500)
+	for range 30 {
+		c.TickFrame()
+	}
+	clock.advance(500 * time.Millisecond)
+	if ips, _ := c.Stats(); ips != 0 {
+		t.Fatalf("Stats refreshed before a second passed: ips = %v", ips)
+	}
+	step(t, c, 500)
+	for range 30 {
+		c.TickFrame()
+	}
+	clock.advance(1500 * time.Millisecond)
+	ips, fps := c.Stats()
+	if ips != 500 || fps != 30 {
+		t.Errorf("Stats = %v, %v over 2s, want 500, 30", ips, fps)
+	}
+}
+
+func TestDrawStats(t *testing.T) {
+	c := newTestChip(t)
+	drawAt(t, c, 3, 4, 0xF0, 0x90) // 6 pixels
+	drawAt(t, c, 67, 4, 0x80)      // wraps to x=3, erasing one
+	want := DrawStats{Sprites: 2, PixelsToggled: 7, Collisions: 1, LastX: 3, LastY: 4, LastHeight: 1}
+	if got := c.DrawStats(); got != want {
+		t.Errorf("DrawStats = %+v, want %+v", got, want)
+	}
+	c.Reset()
+	if got := c.DrawStats(); got != (DrawStats{}) {
+		t.Errorf("DrawStats after Reset = %+v", got)
+	}
+}
+
+func TestRunUnthrottled(t *testing.T) {
+	c := newTestChip(t, 0x7001, 0x1200)
+	c.SetDelayTimer(200)
+	r := c.RunUnthrottled(1000)
+	if r.Err != nil || r.Instructions != 1000 {
+		t.Fatalf("RunUnthrottled ran %d instructions, err %v", r.Instructions, r.Err)
+	}
+	if c.V[0] != 500%256 {
+		t.Errorf("V0 = %d after 1000 instructions, want %d", c.V[0], 500%256)
+	}
+	if c.DelayTimer() != 200-1000/8 {
+		t.Errorf("DT = %d, want %d", c.DelayTimer(), 200-1000/8)
+	}
+	if r.Elapsed <= 0 || r.Elapsed > 10*time.Second {
+		t.Errorf("RunUnthrottled took %v", r.Elapsed)
+	}
+}
+
+func TestRunUnthrottledStopsOnError(t *testing.T) {
+	c := newTestChip(t, 0x7001, 0x7001, 0x00EE, 0x7001)
+	r := c.RunUnthrottled(1000)
+	if r.Err == nil {
+		t.Fatal("00EE on an empty stack did not stop the run")
+	}
+	if r.Instructions != 3 || c.V[0] != 2 {
+		t.Errorf("stopped after %d instructions with V0=%d, want 3 and 2", r.Instructions, c.V[0])
+	}
+}
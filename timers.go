@@ -0,0 +1,94 @@
+package main
+
+// The timer goroutine started by StartTimers decrements DT and ST
+// concurrently with execution, so everything outside that goroutine goes
+// through these accessors.
+
+func (c *Chip8) DelayTimer() byte {
+	c.timerMu.Lock()
+	defer c.timerMu.Unlock()
+	return c.DT
+}
+
+func (c *Chip8) SoundTimer() byte {
+	c.timerMu.Lock()
+	defer c.timerMu.Unlock()
+	return c.ST
+}
+
+func (c *Chip8) SetDelayTimer(v byte) {
+	c.timerMu.Lock()
+	defer c.timerMu.Unlock()
+	c.DT = v
+}
+
+// SetSoundTimer sets ST as FX18 does, starting the beep at once when v is
+// nonzero and stopping it when v is zero. A nonzero v keeps the beep going
+// for at least MinBeepFrames frames, even after ST reaches zero.
+func (c *Chip8) SetSoundTimer(v byte) {
+	c.timerMu.Lock()
+	defer c.timerMu.Unlock()
+	c.ST = v
+	if v > 0 {
+		c.beepFor = c.MinBeepFrames
+		c.startBeepLocked()
+	} else {
+		c.beepFor = 0
+		c.stopBeepLocked()
+	}
+}
+
+func (c *Chip8) tickTimers() {
+	c.timerMu.Lock()
+	defer c.timerMu.Unlock()
+	if c.DT > 0 {
+		c.DT--
+	}
+	if c.beepFor > 0 {
+		c.beepFor--
+	}
+	if c.ST > 0 {
+		c.ST--
+		c.startBeepLocked()
+	} else if c.beepFor == 0 {
+		c.stopBeepLocked()
+	}
+}
+
+// Beeping reports whether the beeper is sounding, whether or not audio is
+// available, so a host can show it visually.
+func (c *Chip8) Beeping() bool {
+	c.timerMu.Lock()
+	defer c.timerMu.Unlock()
+	return c.beeping
+}
+
+func (c *Chip8) startBeepLocked() {
+	if !c.beeping {
+		if !c.FrameAudio {
+			PlayBeep()
+		}
+		c.beeping = true
+		if c.OnBeep != nil {
+			c.OnBeep()
+		}
+	}
+}
+
+func (c *Chip8) stopBeep() {
+	c.timerMu.Lock()
+	defer c.timerMu.Unlock()
+	c.stopBeepLocked()
+}
+
+func (c *Chip8) stopBeepLocked() {
+	if c.beeping {
+		if !c.FrameAudio {
+			StopBeep()
+		}
+		c.beeping = false
+		if c.OnBeepEnd != nil {
+			c.OnBeepEnd()
+		}
+	}
+}
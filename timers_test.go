@@ -0,0 +1,133 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSyncTimersRate(t *testing.T) {
+	for _, hz := range []int{30, 60, 120} {
+		c := &Chip8{TimerHz: hz}
+		c.Init()
+		clock := withFakeClock(c)
+		c.SetDelayTimer(200)
+		c.SyncTimers()
+		clock.advance(time.Second)
+		c.SyncTimers()
+		if got := 200 - int(c.DelayTimer()); got != hz {
+			t.Errorf("TimerHz=%d: %d ticks in a second", hz, got)
+		}
+	}
+}
+
+func TestSyncTimersZeroTimerHz(t *testing.T) {
+	c := &Chip8{}
+	clock := withFakeClock(c)
+	c.DT = 100
+	c.SyncTimers()
+	clock.advance(time.Second)
+	c.SyncTimers()
+	if c.DelayTimer() != 40 {
+		t.Errorf("DT = %d after a second without Init, want 40", c.DelayTimer())
+	}
+}
+
+// TestTimersConcurrentAccess ticks the timers from one goroutine, as
+// StartTimers does, while this one reads and sets them; run it with -race.
+func TestTimersConcurrentAccess(t *testing.T) {
+	c := newTestChip(t)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				c.tickTimers()
+			}
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		c.SetDelayTimer(byte(i))
+		c.SetSoundTimer(byte(i))
+		c.DelayTimer()
+		c.SoundTimer()
+		c.Beeping()
+	}
+	close(stop)
+	<-done
+}
+
+func TestSetSoundTimerBeeps(t *testing.T) {
+	c := newTestChip(t)
+	c.SetSoundTimer(2)
+	if !c.Beeping() {
+		t.Fatal("not beeping after SetSoundTimer(2)")
+	}
+	c.SetSoundTimer(0)
+	if c.Beeping() {
+		t.Error("still beeping after SetSoundTimer(0)")
+	}
+}
+
+func TestMinBeepFrames(t *testing.T) {
+	c := newTestChip(t)
+	c.MinBeepFrames = 4
+	c.SetSoundTimer(1)
+	for frame := 1; frame < 4; frame++ {
+		c.TickFrame()
+		if !c.Beeping() {
+			t.Fatalf("beep stopped after %d frames, want at least 4", frame)
+		}
+	}
+	c.TickFrame()
+	if c.Beeping() || c.SoundTimer() != 0 {
+		t.Errorf("after 4 frames beeping=%v ST=%d", c.Beeping(), c.SoundTimer())
+	}
+}
+
+func TestMinBeepFramesOff(t *testing.T) {
+	c := newTestChip(t)
+	c.SetSoundTimer(1)
+	c.TickFrame()
+	c.TickFrame()
+	if c.Beeping() {
+		t.Error("ST=1 beep still sounding two frames later")
+	}
+}
+
+func TestOnBeepCallbacks(t *testing.T) {
+	c := newTestChip(t, 0x6002, 0xF018)
+	var events []string
+	c.OnBeep = func() { events = append(events, "beep") }
+	c.OnBeepEnd = func() { events = append(events, "end") }
+	step(t, c, 2)
+	if len(events) != 1 || events[0] != "beep" {
+		t.Fatalf("after FX18 events = %v, want [beep]", events)
+	}
+	c.TickFrame()
+	c.TickFrame()
+	if len(events) != 1 {
+		t.Fatalf("events = %v while ST counted down", events)
+	}
+	c.TickFrame()
+	if len(events) != 2 || events[1] != "end" {
+		t.Errorf("after ST reached zero events = %v, want [beep end]", events)
+	}
+}
+
+func TestTimersZeroAfterInit(t *testing.T) {
+	c := &Chip8{}
+	beeps := 0
+	c.OnBeep = func() { beeps++ }
+	c.Init()
+	if err := c.LoadEmbeddedROM("ibm"); err != nil {
+		t.Fatal(err)
+	}
+	if c.DelayTimer() != 0 || c.SoundTimer() != 0 || c.Beeping() || beeps != 0 {
+		t.Errorf("after Init and load DT=%d ST=%d beeping=%v beeps=%d, want all zero",
+			c.DelayTimer(), c.SoundTimer(), c.Beeping(), beeps)
+	}
+}
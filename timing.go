@@ -0,0 +1,44 @@
+package main
+
+// vipClockHz is the COSMAC VIP machine-cycle rate (1.76MHz / 8).
+const vipClockHz = 220080
+
+// cycleCost returns the approximate number of COSMAC VIP machine cycles an
+// instruction takes. Data-dependent instructions use their typical cost.
+func cycleCost(opcode uint16) int {
+	switch mnemonic(opcode) {
+	case "00E0":
+		return 24
+	case "00EE":
+		return 10
+	case "1NNN", "ANNN":
+		return 12
+	case "2NNN":
+		return 26
+	case "3XNN", "4XNN", "FX07", "FX15", "FX18", "7XNN":
+		return 10
+	case "5XY0", "9XY0", "EX9E", "EXA1":
+		return 14
+	case "6XNN":
+		return 6
+	case "8XY0":
+		return 12
+	case "8XY1", "8XY2", "8XY3", "8XY4", "8XY5", "8XY6", "8XY7", "8XYE":
+		return 44
+	case "BNNN":
+		return 22
+	case "CXNN":
+		return 36
+	case "DXYN":
+		return 26 + 68*int(opcode&0x000F)
+	case "FX0A":
+		return 48
+	case "FX1E", "FX29", "FX30":
+		return 16
+	case "FX33":
+		return 80
+	case "FX55", "FX65":
+		return 14 + 14*int((opcode&0x0F00)>>8+1)
+	}
+	return 12
+}
@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestCycleCost(t *testing.T) {
+	for _, tc := range []struct {
+		opcode uint16
+		want   int
+	}{
+		{0x00E0, 24},
+		{0x00EE, 10},
+		{0x1234, 12},
+		{0x6012, 6},
+		{0x8124, 44},
+		{0xD015, 26 + 68*5},
+		{0xD010, 26},
+		{0xF033, 80},
+		{0xF055, 28},
+		{0xF365, 70},
+	} {
+		if got := cycleCost(tc.opcode); got != tc.want {
+			t.Errorf("cycleCost(%04X) = %d, want %d", tc.opcode, got, tc.want)
+		}
+	}
+}
+
+func TestAccurateTimingBudget(t *testing.T) {
+	// 6XNN costs 6 cycles, so a 600-cycle frame runs 100 of them.
+	program := make([]uint16, 200)
+	for i := range program {
+		program[i] = 0x6000
+	}
+	c := newTestChip(t, program...)
+	c.AccurateTiming = true
+	c.ClockHz = 600 * 60
+	if err := c.RunFrame(); err != nil {
+		t.Fatal(err)
+	}
+	if c.PC != 0x200+2*100 {
+		t.Errorf("frame ended at %03X, want %03X", c.PC, 0x200+2*100)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type traceRegs struct {
+	V  [16]byte
+	I  uint16
+	SP byte
+	DT byte
+	ST byte
+}
+
+func (c *Chip8) traceRegs() traceRegs {
+	return traceRegs{V: c.V, I: c.I, SP: c.SP, DT: c.DelayTimer(), ST: c.SoundTimer()}
+}
+
+// WriteTrace enables a replayable trace with one line per instruction:
+// "cycle pc opcode changes", where changes lists the registers the
+// instruction modified, e.g. "12 206 6108 V1=08". A nil writer disables it.
+func (c *Chip8) WriteTrace(w io.Writer) {
+	c.trace = w
+}
+
+func (c *Chip8) writeTraceLine(before traceRegs, opcode uint16) {
+	after := c.traceRegs()
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d %03X %04X", c.Cycles, c.opPC, opcode)
+	for i := range after.V {
+		if after.V[i] != before.V[i] {
+			fmt.Fprintf(&b, " V%X=%02X", i, after.V[i])
+		}
+	}
+	if after.I != before.I {
+		fmt.Fprintf(&b, " I=%03X", after.I)
+	}
+	if after.SP != before.SP {
+		fmt.Fprintf(&b, " SP=%X", after.SP)
+	}
+	if after.DT != before.DT {
+		fmt.Fprintf(&b, " DT=%02X", after.DT)
+	}
+	if after.ST != before.ST {
+		fmt.Fprintf(&b, " ST=%02X", after.ST)
+	}
+	b.WriteByte('\n')
+	io.WriteString(c.trace, b.String())
+}
+
+// WriteRegisterLog enables a second trace holding the full register state
+// before each instruction runs, for comparing two runs line by line with
+// DiffTrace when the change lists of WriteTrace are not enough:
+//
+//	pc=0200 op=6108 i=0000 v0=00 v1=00 ... vf=00
+//
+// with the address and opcode as four hex digits, i as four, and each of
+// v0 through vf as two, all lowercase. A nil writer disables it.
+func (c *Chip8) WriteRegisterLog(w io.Writer) {
+	c.regLog = w
+}
+
+func (c *Chip8) writeRegisterLine(opcode uint16) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "pc=%04x op=%04x i=%04x", c.opPC, opcode, c.I)
+	for i, v := range c.V {
+		fmt.Fprintf(&b, " v%x=%02x", i, v)
+	}
+	b.WriteByte('\n')
+	io.WriteString(c.regLog, b.String())
+}
+
+// DiffTrace compares two traces and returns the 1-based line of the first
+// divergence, or 0 when they are identical.
+func DiffTrace(a, b io.Reader) (int, error) {
+	sa, sb := bufio.NewScanner(a), bufio.NewScanner(b)
+	for line := 1; ; line++ {
+		okA, okB := sa.Scan(), sb.Scan()
+		if !okA || !okB {
+			if err := sa.Err(); err != nil {
+				return 0, err
+			}
+			if err := sb.Err(); err != nil {
+				return 0, err
+			}
+			if okA != okB {
+				return line, nil
+			}
+			return 0, nil
+		}
+		if sa.Text() != sb.Text() {
+			return line, nil
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func traceOf(t *testing.T, program ...uint16) string {
+	t.Helper()
+	c := newTestChip(t, program...)
+	var b strings.Builder
+	c.WriteTrace(&b)
+	step(t, c, len(program))
+	return b.String()
+}
+
+func TestWriteTrace(t *testing.T) {
+	got := traceOf(t, 0x6108, 0xA300, 0x7101, 0xF015)
+	want := "1 200 6108 V1=08\n2 202 A300 I=300\n3 204 7101 V1=09\n4 206 F015\n"
+	if got != want {
+		t.Errorf("trace:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDiffTrace(t *testing.T) {
+	trace := traceOf(t, 0x6108, 0xA300, 0x7101, 0x6205)
+	mutated := strings.Replace(trace, "V1=09", "V1=0A", 1)
+	for _, tc := range []struct {
+		name string
+		b    string
+		want int
+	}{
+		{"identical", trace, 0},
+		{"mutated third line", mutated, 3},
+		{"truncated", strings.Join(strings.SplitAfter(trace, "\n")[:2], ""), 3},
+	} {
+		line, err := DiffTrace(strings.NewReader(trace), strings.NewReader(tc.b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line != tc.want {
+			t.Errorf("%s: DiffTrace = %d, want %d", tc.name, line, tc.want)
+		}
+	}
+}
+
+func TestWriteRegisterLog(t *testing.T) {
+	c := newTestChip(t, 0x61AB, 0xA2F0, 0x1204)
+	var b strings.Builder
+	c.WriteRegisterLog(&b)
+	step(t, c, 2)
+	regs := func(v1 string) string {
+		return " v0=00 v1=" + v1 + " v2=00 v3=00 v4=00 v5=00 v6=00 v7=00" +
+			" v8=00 v9=00 va=00 vb=00 vc=00 vd=00 ve=00 vf=00\n"
+	}
+	want := "pc=0200 op=61ab i=0000" + regs("00") +
+		"pc=0202 op=a2f0 i=0000" + regs("ab")
+	if b.String() != want {
+		t.Errorf("register log:\n%s\nwant:\n%s", b.String(), want)
+	}
+	c.WriteRegisterLog(nil)
+	step(t, c, 1)
+	if strings.Count(b.String(), "\n") != 2 {
+		t.Error("trace still written after WriteRegisterLog(nil)")
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import "errors"
+
+// undoEntry holds what one instruction can change: the registers, the 16
+// bytes from I that FX33/FX55 write, and the display only for opcodes that
+// draw or clear.
+type undoEntry struct {
+	state          State
+	opcode         uint16
+	display        *[128][64]byte
+	hires          bool
+	planes         byte
+	pitch          byte
+	keys           [16]bool
+	keyUnread      [16]bool
+	keyReleasing   [16]bool
+	keyWaited      [16]bool
+	keyScript      []keyEvent
+	waitKey        byte
+	waitingRelease bool
+	drawStats      DrawStats
+	cycles         uint64
+}
+
+func (c *Chip8) pushUndo() {
+	e := undoEntry{
+		state:          c.state(),
+		opcode:         c.Peek(),
+		hires:          c.hires,
+		planes:         c.planes,
+		pitch:          c.pitch,
+		keys:           c.keys,
+		keyUnread:      c.keyUnread,
+		keyReleasing:   c.keyReleasing,
+		keyWaited:      c.keyWaited,
+		keyScript:      c.keyScript,
+		waitKey:        c.waitKey,
+		waitingRelease: c.waitingRelease,
+		drawStats:      c.drawStats,
+		cycles:         c.Cycles,
+	}
+	e.state.Memory = make(map[uint16]byte, 16)
+	for i := 0; i < 16; i++ {
+		if addr := c.iAddr(i); addr < len(c.memory) {
+			e.state.Memory[uint16(addr)] = c.memory[addr]
+		}
+	}
+	if e.opcode&0xF000 == 0xD000 || e.opcode&0xFF00 == 0x0000 {
+		d := c.display
+		e.display = &d
+	}
+	c.undoLog = append(c.undoLog, e)
+	if n := len(c.undoLog) - c.UndoDepth; n > 0 {
+		c.undoLog = append(c.undoLog[:0], c.undoLog[n:]...)
+	}
+}
+
+// Undo reverts the last instruction run by Step, up to UndoDepth steps back.
+func (c *Chip8) Undo() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.undoLog) == 0 {
+		return errors.New("nothing to undo")
+	}
+	e := c.undoLog[len(c.undoLog)-1]
+	c.undoLog = c.undoLog[:len(c.undoLog)-1]
+	if err := c.setState(e.state); err != nil {
+		return err
+	}
+	if e.display != nil {
+		c.display = *e.display
+		c.markAllDirty()
+	}
+	c.hires = e.hires
+	c.planes = e.planes
+	c.pitch = e.pitch
+	SetBeepFrequency(pitchFrequency(c.pitch))
+	c.keys = e.keys
+	c.keyUnread = e.keyUnread
+	c.keyReleasing = e.keyReleasing
+	c.keyWaited = e.keyWaited
+	c.keyScript = e.keyScript
+	c.waitKey = e.waitKey
+	c.waitingRelease = e.waitingRelease
+	c.drawStats = e.drawStats
+	if c.Cycles > e.cycles {
+		c.opCounts[mnemonic(e.opcode)]--
+	}
+	c.Cycles = e.cycles
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestUndoRegistersAndMemory(t *testing.T) {
+	c := newTestChip(t, 0x6005, 0x7003, 0xA300, 0xF055)
+	c.UndoDepth = 8
+	step(t, c, 4)
+	if c.memory[0x300] != 8 {
+		t.Fatalf("FX55 stored %d", c.memory[0x300])
+	}
+	for i := 0; i < 4; i++ {
+		if err := c.Undo(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.PC != 0x200 || c.V[0] != 0 || c.I != 0 || c.memory[0x300] != 0 || c.Cycles != 0 {
+		t.Errorf("after undoing everything PC=%03X V0=%d I=%03X mem=%d cycles=%d", c.PC, c.V[0], c.I, c.memory[0x300], c.Cycles)
+	}
+	if err := c.Undo(); err == nil {
+		t.Error("Undo with an empty log succeeded")
+	}
+}
+
+func TestUndoDraw(t *testing.T) {
+	c := newTestChip(t, 0xD005, 0x00E0)
+	c.UndoDepth = 8
+	step(t, c, 1)
+	drawn := c.display
+	step(t, c, 1)
+	if err := c.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if c.display != drawn {
+		t.Error("undoing 00E0 did not restore the drawn sprite")
+	}
+	if err := c.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if c.display != ([128][64]byte{}) {
+		t.Error("undoing DXYN did not clear the sprite")
+	}
+}
+
+func TestUndoPitchRestoresBeepFrequency(t *testing.T) {
+	c := &Chip8{Variant: VariantXOChip}
+	c.Init()
+	loadProgram(t, c, 0x6080, 0xF03A)
+	c.UndoDepth = 8
+	step(t, c, 2)
+	if currentBeepFrequency() == baseBeepFrequency {
+		t.Fatal("FX3A did not change the beep frequency")
+	}
+	if err := c.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if c.pitch != 64 || currentBeepFrequency() != baseBeepFrequency {
+		t.Errorf("after Undo pitch=%d frequency=%v, want 64 and %v", c.pitch, currentBeepFrequency(), baseBeepFrequency)
+	}
+}